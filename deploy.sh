@@ -54,6 +54,12 @@ sed -i '' '/^toolchain/d' go.mod
 echo "Running go mod tidy..."
 go mod tidy -e
 
+# Create the secret that Cloud Tasks send to the /api/tasks routes if it doesn't exist yet
+if ! gcloud secrets describe TASKS_SECRET &>/dev/null; then
+    echo "Creating TASKS_SECRET..."
+    openssl rand -hex 32 | tr -d '\n' | gcloud secrets create TASKS_SECRET --replication-policy automatic --data-file=-
+fi
+
 # Build and deploy to Cloud Run
 echo "Deploying to Cloud Run..."
 gcloud run deploy $SERVER_SERVICE_NAME \
@@ -65,7 +71,8 @@ gcloud run deploy $SERVER_SERVICE_NAME \
   --cpu 1 \
   --memory 512Mi \
   --max-instances 10 \
-  --min-instances 1
+  --min-instances 1 \
+  --update-secrets TASKS_SECRET=TASKS_SECRET:latest
 
 # Restore original go.mod
 echo "Restoring original go.mod..."
@@ -75,6 +82,25 @@ mv go.mod.backup go.mod
 BACKEND_URL=$(gcloud run services describe $SERVER_SERVICE_NAME --region $REGION --format="value(status.url)")
 echo "Backend URL: $BACKEND_URL"
 
+# Purge the events that have been in the trash for longer than the retention period every day
+echo "Scheduling the purge of deleted events..."
+TASKS_SECRET=$(gcloud secrets versions access latest --secret TASKS_SECRET)
+if gcloud scheduler jobs describe purge-deleted-events --location $REGION &>/dev/null; then
+  gcloud scheduler jobs update http purge-deleted-events \
+    --location $REGION \
+    --schedule "0 3 * * *" \
+    --uri "$BACKEND_URL/api/tasks/purge-deleted-events" \
+    --http-method POST \
+    --update-headers "X-Tasks-Secret=$TASKS_SECRET"
+else
+  gcloud scheduler jobs create http purge-deleted-events \
+    --location $REGION \
+    --schedule "0 3 * * *" \
+    --uri "$BACKEND_URL/api/tasks/purge-deleted-events" \
+    --http-method POST \
+    --headers "X-Tasks-Secret=$TASKS_SECRET"
+fi
+
 # Return to root directory
 cd ..
 
@@ -47,7 +47,7 @@ class AuthService extends ChangeNotifier {
         'profile',
         'email',
         'https://www.googleapis.com/auth/calendar.calendarlist.readonly',
-        'https://www.googleapis.com/auth/calendar.events.readonly',
+        'https://www.googleapis.com/auth/calendar.events',
       ],
     );
 
@@ -20,6 +20,7 @@ export const errors = Object.freeze({
   UserDoesNotExist: "user-does-not-exist",
   EventNotFound: "event-not-found",
   InvalidCredentials: "invalid-credentials",
+  CalendarNeedsReauth: "calendar-needs-reauth",
 })
 
 // Auth types
@@ -108,7 +108,7 @@ export default new Vuex.Store({
         const provider = new GoogleAuthProvider()
         // Add scopes for calendar access
         provider.addScope("https://www.googleapis.com/auth/calendar.calendarlist.readonly")
-        provider.addScope("https://www.googleapis.com/auth/calendar.events.readonly")
+        provider.addScope("https://www.googleapis.com/auth/calendar.events")
         
         const result = await signInWithPopup(auth, provider)
         
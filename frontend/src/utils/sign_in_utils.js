@@ -16,7 +16,7 @@ export const signInGoogle = ({
   let scope = "openid email profile "
   if (requestCalendarPermission) {
     scope +=
-      "https://www.googleapis.com/auth/calendar.calendarlist.readonly https://www.googleapis.com/auth/calendar.events.readonly "
+      "https://www.googleapis.com/auth/calendar.calendarlist.readonly https://www.googleapis.com/auth/calendar.events "
   }
   if (requestContactsPermission) {
     scope +=
@@ -56,7 +56,7 @@ export const signInOutlook = ({
 
   let scope = "offline_access User.Read"
   if (requestCalendarPermission) {
-    scope += " Calendars.Read"
+    scope += " Calendars.ReadWrite"
   }
   scope = encodeURIComponent(scope)
 
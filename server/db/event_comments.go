@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the comments on the given event from oldest to newest
+func GetEventComments(eventId primitive.ObjectID) []models.EventComment {
+	comments := make([]models.EventComment, 0)
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := EventCommentsCollection.Find(context.Background(), bson.M{"eventId": eventId}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if err := cursor.All(context.Background(), &comments); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return comments
+}
+
+// Returns the comment with the given id on the given event, or nil if it doesn't exist
+func GetEventComment(eventId primitive.ObjectID, commentId string) *models.EventComment {
+	objectId, err := primitive.ObjectIDFromHex(commentId)
+	if err != nil {
+		return nil
+	}
+
+	var comment models.EventComment
+	err = EventCommentsCollection.FindOne(context.Background(), bson.M{"_id": objectId, "eventId": eventId}).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &comment
+}
+
+// Adds the comment and sets its id
+func InsertEventComment(comment *models.EventComment) error {
+	result, err := EventCommentsCollection.InsertOne(context.Background(), comment)
+	if err != nil {
+		return err
+	}
+
+	comment.Id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Deletes the comment with the given id
+func DeleteEventComment(commentId primitive.ObjectID) error {
+	_, err := EventCommentsCollection.DeleteOne(context.Background(), bson.M{"_id": commentId})
+	return err
+}
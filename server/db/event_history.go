@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Appends the entries to the history of their events
+func InsertEventHistoryEntries(entries []models.EventHistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		documents[i] = entry
+	}
+
+	_, err := EventHistoryCollection.InsertMany(context.Background(), documents)
+	return err
+}
+
+// Returns the history of the given event from newest to oldest
+func GetEventHistory(eventId primitive.ObjectID) []models.EventHistoryEntry {
+	entries := make([]models.EventHistoryEntry, 0)
+	opts := options.Find().SetSort(bson.M{"_id": -1})
+
+	cursor, err := EventHistoryCollection.Find(context.Background(), bson.M{"eventId": eventId}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return entries
+}
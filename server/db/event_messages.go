@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/models"
+)
+
+// Inserts the live update message, which is delivered to every change stream on the messages
+func InsertEventMessage(message *models.EventMessage) error {
+	_, err := EventMessagesCollection.InsertOne(context.Background(), message)
+	return err
+}
+
+// Opens a change stream of the inserted live update messages, starting after the given resume token if it isn't nil.
+// Fails if the database doesn't support change streams, i.e. it isn't a replica set
+func WatchEventMessages(ctx context.Context, resumeToken bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	opts := options.ChangeStream()
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+	return EventMessagesCollection.Watch(ctx, pipeline, opts)
+}
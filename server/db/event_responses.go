@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the availability responses to the given event in the order they were first submitted
+func GetEventResponses(eventId primitive.ObjectID) []models.EventResponse {
+	eventResponses := make([]models.EventResponse, 0)
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := EventResponsesCollection.Find(context.Background(), bson.M{"eventId": eventId}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if err := cursor.All(context.Background(), &eventResponses); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return eventResponses
+}
+
+// Returns the ids of the events that the given user has responded to
+func GetRespondedEventIds(userId string) []primitive.ObjectID {
+	eventIds, err := EventResponsesCollection.Distinct(context.Background(), "eventId", bson.M{"userId": userId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	result := make([]primitive.ObjectID, 0)
+	for _, eventId := range eventIds {
+		if objectId, ok := eventId.(primitive.ObjectID); ok {
+			result = append(result, objectId)
+		}
+	}
+	return result
+}
+
+// Sets the ResponsesList of each event to the ids of its respondents without their responses,
+// which is enough for showing the number of people who responded
+func populateRespondentIds(events []models.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	eventIds := make([]primitive.ObjectID, len(events))
+	for i, event := range events {
+		eventIds[i] = event.Id
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetProjection(bson.M{"eventId": 1, "userId": 1})
+	cursor, err := EventResponsesCollection.Find(context.Background(), bson.M{"eventId": bson.M{"$in": eventIds}}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	var eventResponses []models.EventResponse
+	if err := cursor.All(context.Background(), &eventResponses); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	respondents := make(map[primitive.ObjectID][]models.EventResponse)
+	for _, eventResponse := range eventResponses {
+		respondents[eventResponse.EventId] = append(respondents[eventResponse.EventId], models.EventResponse{UserId: eventResponse.UserId})
+	}
+	for i := range events {
+		events[i].ResponsesList = respondents[events[i].Id]
+		if events[i].ResponsesList == nil {
+			events[i].ResponsesList = make([]models.EventResponse, 0)
+		}
+	}
+}
+
+// Creates or replaces the response of the given user to the given event. Returns whether the response is new
+func UpsertEventResponse(eventId primitive.ObjectID, userId string, response *models.Response) (bool, error) {
+	result, err := EventResponsesCollection.UpdateOne(
+		context.Background(),
+		bson.M{"eventId": eventId, "userId": userId},
+		bson.M{"$set": bson.M{"response": response}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.UpsertedCount > 0, nil
+}
+
+// Adds the given responses to the event, e.g. when copying the availability of a duplicated event
+func InsertEventResponses(eventId primitive.ObjectID, eventResponses []models.EventResponse) error {
+	if len(eventResponses) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(eventResponses))
+	for i, eventResponse := range eventResponses {
+		documents[i] = models.EventResponse{
+			EventId:  eventId,
+			UserId:   eventResponse.UserId,
+			Response: eventResponse.Response,
+		}
+	}
+
+	_, err := EventResponsesCollection.InsertMany(context.Background(), documents)
+	return err
+}
+
+// Deletes the response of the given user to the given event
+func DeleteEventResponse(eventId primitive.ObjectID, userId string) error {
+	_, err := EventResponsesCollection.DeleteOne(context.Background(), bson.M{"eventId": eventId, "userId": userId})
+	return err
+}
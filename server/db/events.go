@@ -2,29 +2,279 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
 )
 
-// AddGoogleMeetLinkToEvent adds a Google Meet link to an event
-func AddGoogleMeetLinkToEvent(eventId string, meetLink string, startTime string, endTime string) error {
+// Returned by UpdateEvent when the event was changed by someone else since it was fetched
+var ErrEventVersionConflict = errors.New("event was modified concurrently")
+
+// UpdateEvent saves the event if nobody else changed it since it was fetched (i.e. its version is still the
+// version in the database), and increments its version. Returns ErrEventVersionConflict otherwise
+func UpdateEvent(event *models.Event) error {
+	filter := bson.M{"_id": event.Id, "version": event.Version}
+	if event.Version == 0 {
+		// Events created before versioning don't have a version yet
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	event.Version++
+	update := bson.M{"$set": event}
+	if event.EditToken == nil {
+		// $set leaves out the edit token once it is cleared, so remove it explicitly
+		update["$unset"] = bson.M{"editToken": ""}
+	}
+	result, err := EventsCollection.UpdateOne(context.Background(), filter, update)
+	if err == nil && result.MatchedCount == 0 {
+		err = ErrEventVersionConflict
+	}
+	if err != nil {
+		event.Version--
+		return err
+	}
+
+	return nil
+}
+
+// Sets the sign up response of the given user without touching the rest of the event, so concurrent sign ups
+// don't overwrite each other. Returns whether the user hadn't signed up before
+func SetSignUpResponse(eventId primitive.ObjectID, userId string, response *models.SignUpResponse) (bool, error) {
+	return updateSignUpResponse(eventId, userId, bson.M{"$literal": response})
+}
+
+// Removes the sign up response of the given user without touching the rest of the event
+func DeleteSignUpResponse(eventId primitive.ObjectID, userId string) error {
+	_, err := updateSignUpResponse(eventId, userId, "$$REMOVE")
+	return err
+}
+
+// Sets the sign up response of the given user to value and increments the event's version. Returns whether the
+// user didn't have a sign up response before
+func updateSignUpResponse(eventId primitive.ObjectID, userId string, value interface{}) (bool, error) {
+	pipeline := bson.A{bson.M{"$set": bson.M{
+		"signUpResponses": bson.M{
+			"$setField": bson.M{
+				"field": bson.M{"$literal": userId}, // Use $setField because userId could have periods
+				"input": bson.M{"$ifNull": bson.A{"$signUpResponses", bson.M{}}},
+				"value": value,
+			},
+		},
+		"version": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+	}}}
+
+	opts := options.FindOneAndUpdate().
+		SetProjection(bson.M{"signUpResponses": 1}).
+		SetReturnDocument(options.Before)
+	result := EventsCollection.FindOneAndUpdate(context.Background(), bson.M{"_id": eventId}, pipeline, opts)
+
+	var before models.Event
+	if err := result.Decode(&before); err != nil {
+		return false, err
+	}
+	_, existed := before.SignUpResponses[userId]
+	return !existed, nil
+}
+
+// AddMeetLinkToEvent adds a video conferencing link (e.g. Google Meet or Teams) to an event.
+// Empty start and end times are removed, since not every provider schedules the meeting for a specific time
+func AddMeetLinkToEvent(eventId string, provider models.ConferencingProvider, meetLink string, calendarEvent *models.ConferenceCalendarEvent, startTime string, endTime string) error {
 	objId, err := primitive.ObjectIDFromHex(eventId)
 	if err != nil {
 		return err
 	}
 
+	set := bson.M{
+		"meetLink":     meetLink,
+		"meetProvider": provider,
+		"updatedAt":    time.Now(),
+	}
+	unset := bson.M{}
+	if len(startTime) > 0 && len(endTime) > 0 {
+		set["meetStartTime"] = startTime
+		set["meetEndTime"] = endTime
+	} else {
+		unset["meetStartTime"] = ""
+		unset["meetEndTime"] = ""
+	}
+	if calendarEvent != nil {
+		set["meetCalendarEvent"] = calendarEvent
+	} else {
+		unset["meetCalendarEvent"] = ""
+	}
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
 	filter := bson.M{"_id": objId}
-	update := bson.M{
-		"$set": bson.M{
-			"meetLink":        meetLink,
-			"meetStartTime":   startTime,
-			"meetEndTime":     endTime,
-			"updatedAt":       time.Now(),
+	_, err = EventsCollection.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+// RemoveMeetLinkFromEvent removes the video conferencing link from an event
+func RemoveMeetLinkFromEvent(eventId primitive.ObjectID) error {
+	_, err := EventsCollection.UpdateByID(context.Background(), eventId, bson.M{
+		"$unset": bson.M{
+			"meetLink":          "",
+			"meetProvider":      "",
+			"meetStartTime":     "",
+			"meetEndTime":       "",
+			"meetCalendarEvent": "",
+		},
+		"$inc": bson.M{"version": 1},
+	})
+	return err
+}
+
+// Returns all the events the user owns, has responded to, or is an attendee of, newest first
+func GetEventsForUser(user *models.User) []models.Event {
+	events := make([]models.Event, 0)
+	opts := options.Find().SetSort(bson.M{"_id": -1})
+
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"$or": bson.A{
+			bson.M{"ownerId": user.Id},
+			bson.M{"collaborators.userId": user.Id},
+			bson.M{"_id": bson.M{"$in": GetRespondedEventIds(user.Id.Hex())}},
+			bson.M{"attendees": bson.M{"email": user.Email, "declined": false}},
 		},
+		"deletedAt": nil,
+	}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
 	}
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	populateRespondentIds(events)
 
-	_, err = EventsCollection.UpdateOne(context.Background(), filter, update)
+	return events
+}
+
+// Returns the events in the trash that the given user can restore, i.e. the events they are an owner of that were
+// deleted within the retention period
+func GetDeletedEventsForUser(user *models.User) []models.Event {
+	events := make([]models.Event, 0)
+	opts := options.Find().SetSort(bson.M{"deletedAt": -1})
+
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"$or": bson.A{
+			bson.M{"ownerId": user.Id},
+			bson.M{"collaborators": bson.M{"$elemMatch": bson.M{"userId": user.Id, "role": models.OwnerRole}}},
+		},
+		"deletedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(time.Now().Add(-models.EventRetentionPeriod))},
+	}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	populateRespondentIds(events)
+
+	return events
+}
+
+// Moves the event to the trash. Returns whether the event was deleted, i.e. it wasn't in the trash already
+func SoftDeleteEvent(eventId primitive.ObjectID) (bool, error) {
+	result, err := EventsCollection.UpdateOne(context.Background(), bson.M{
+		"_id":       eventId,
+		"deletedAt": nil,
+	}, bson.M{
+		"$set": bson.M{"deletedAt": primitive.NewDateTimeFromTime(time.Now())},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// Takes the event out of the trash
+func RestoreEvent(eventId primitive.ObjectID) error {
+	_, err := EventsCollection.UpdateOne(context.Background(), bson.M{"_id": eventId}, bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$inc":   bson.M{"version": 1},
+	})
 	return err
-} 
\ No newline at end of file
+}
+
+// Permanently deletes the events that were moved to the trash before the given time, along with their responses, history, comments, and poll votes.
+// Returns the number of events that were deleted
+func PurgeDeletedEvents(deletedBefore time.Time) (int, error) {
+	filter := bson.M{"deletedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(deletedBefore)}}
+	eventIds, err := EventsCollection.Distinct(context.Background(), "_id", filter)
+	if err != nil {
+		return 0, err
+	}
+	if len(eventIds) == 0 {
+		return 0, nil
+	}
+
+	if _, err := EventResponsesCollection.DeleteMany(context.Background(), bson.M{"eventId": bson.M{"$in": eventIds}}); err != nil {
+		return 0, err
+	}
+	if _, err := EventHistoryCollection.DeleteMany(context.Background(), bson.M{"eventId": bson.M{"$in": eventIds}}); err != nil {
+		return 0, err
+	}
+	if _, err := EventCommentsCollection.DeleteMany(context.Background(), bson.M{"eventId": bson.M{"$in": eventIds}}); err != nil {
+		return 0, err
+	}
+	if _, err := PollVotesCollection.DeleteMany(context.Background(), bson.M{"eventId": bson.M{"$in": eventIds}}); err != nil {
+		return 0, err
+	}
+	filter["_id"] = bson.M{"$in": eventIds}
+	result, err := EventsCollection.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+// Marks the event as closed if it still closes at the given time and hasn't been closed yet.
+// Returns whether the event was marked as closed
+func CloseEvent(eventId primitive.ObjectID, closesAt primitive.DateTime) (bool, error) {
+	result, err := EventsCollection.UpdateOne(context.Background(), bson.M{
+		"_id":       eventId,
+		"closesAt":  closesAt,
+		"closed":    bson.M{"$ne": true},
+		"deletedAt": nil,
+	}, bson.M{
+		"$set": bson.M{"closed": true},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// Returns the user with the given calendar feed token
+func GetUserByCalendarFeedToken(token string) *models.User {
+	result := UsersCollection.FindOne(context.Background(), bson.M{
+		"calendarFeedToken": token,
+	})
+	if result.Err() == mongo.ErrNoDocuments {
+		// User does not exist!
+		return nil
+	}
+
+	// Decode result
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &user
+}
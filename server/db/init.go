@@ -13,6 +13,11 @@ import (
 var Client *mongo.Client
 var Db *mongo.Database
 var EventsCollection *mongo.Collection
+var EventResponsesCollection *mongo.Collection
+var EventHistoryCollection *mongo.Collection
+var EventCommentsCollection *mongo.Collection
+var PollVotesCollection *mongo.Collection
+var EventMessagesCollection *mongo.Collection
 var UsersCollection *mongo.Collection
 var DailyUserLogCollection *mongo.Collection
 var FriendRequestsCollection *mongo.Collection
@@ -54,6 +59,11 @@ func Init() func() {
 	// Global database
 	Db = client.Database("gatherly")
 	EventsCollection = Db.Collection("events")
+	EventResponsesCollection = Db.Collection("eventresponses")
+	EventHistoryCollection = Db.Collection("eventhistory")
+	EventCommentsCollection = Db.Collection("eventcomments")
+	PollVotesCollection = Db.Collection("pollvotes")
+	EventMessagesCollection = Db.Collection("eventmessages")
 	UsersCollection = Db.Collection("users")
 	DailyUserLogCollection = Db.Collection("dailyuserlogs")
 	FriendRequestsCollection = Db.Collection("friendrequests")
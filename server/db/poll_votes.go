@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the votes on all the polls of the given event in the order they were first cast
+func GetPollVotes(eventId primitive.ObjectID) []models.PollVote {
+	votes := make([]models.PollVote, 0)
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := PollVotesCollection.Find(context.Background(), bson.M{"eventId": eventId}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if err := cursor.All(context.Background(), &votes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return votes
+}
+
+// Creates or replaces the vote of the respondent on the poll
+func UpsertPollVote(vote *models.PollVote) error {
+	_, err := PollVotesCollection.UpdateOne(
+		context.Background(),
+		bson.M{"eventId": vote.EventId, "pollId": vote.PollId, "userId": vote.UserId},
+		bson.M{"$set": bson.M{"name": vote.Name, "optionIds": vote.OptionIds}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Deletes the vote of the given respondent on the poll
+func DeletePollVote(eventId primitive.ObjectID, pollId primitive.ObjectID, userId string) error {
+	_, err := PollVotesCollection.DeleteOne(context.Background(), bson.M{"eventId": eventId, "pollId": pollId, "userId": userId})
+	return err
+}
+
+// Deletes every vote on the poll
+func DeletePollVotes(eventId primitive.ObjectID, pollId primitive.ObjectID) error {
+	_, err := PollVotesCollection.DeleteMany(context.Background(), bson.M{"eventId": eventId, "pollId": pollId})
+	return err
+}
+
+// Deletes the votes of the given respondent on every poll of the event
+func DeleteRespondentPollVotes(eventId primitive.ObjectID, userId string) error {
+	_, err := PollVotesCollection.DeleteMany(context.Background(), bson.M{"eventId": eventId, "userId": userId})
+	return err
+}
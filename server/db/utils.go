@@ -64,7 +64,8 @@ func GetEventById(eventId string) *models.Event {
 		return nil
 	}
 	result := EventsCollection.FindOne(context.Background(), bson.M{
-		"_id": objectId,
+		"_id":       objectId,
+		"deletedAt": nil,
 	})
 	if result.Err() == mongo.ErrNoDocuments {
 		// Event does not exist!
@@ -76,6 +77,7 @@ func GetEventById(eventId string) *models.Event {
 	if err := result.Decode(&event); err != nil {
 		logger.StdErr.Panicln(err)
 	}
+	event.ResponsesList = GetEventResponses(event.Id)
 
 	return &event
 }
@@ -83,13 +85,40 @@ func GetEventById(eventId string) *models.Event {
 // Returns an event based on its shortId
 func GetEventByShortId(shortEventId string) *models.Event {
 	result := EventsCollection.FindOne(context.Background(), bson.M{
-		"shortId": shortEventId,
+		"shortId":   shortEventId,
+		"deletedAt": nil,
 	})
 	if result.Err() == mongo.ErrNoDocuments {
 		// Event does not exist!
 		return nil
 	}
 
+	// Decode result
+	var event models.Event
+	if err := result.Decode(&event); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	event.ResponsesList = GetEventResponses(event.Id)
+
+	return &event
+}
+
+// Returns an event in the trash based on its _id
+func GetDeletedEventById(eventId string) *models.Event {
+	objectId, err := primitive.ObjectIDFromHex(eventId)
+	if err != nil {
+		// eventId is malformatted
+		return nil
+	}
+	result := EventsCollection.FindOne(context.Background(), bson.M{
+		"_id":       objectId,
+		"deletedAt": bson.M{"$ne": nil},
+	})
+	if result.Err() == mongo.ErrNoDocuments {
+		// Event does not exist or isn't deleted
+		return nil
+	}
+
 	// Decode result
 	var event models.Event
 	if err := result.Decode(&event); err != nil {
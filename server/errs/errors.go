@@ -18,6 +18,34 @@ const (
 	AttendeeEmailNotFound string = "attendee-email-not-found"
 	EventNotGroup         string = "event-not-group"
 	InvalidCredentials    string = "invalid-credentials"
+	InvalidTimeRange      string = "invalid-time-range"
+	EventNotScheduled     string = "event-not-scheduled"
+	InvalidCalendarUrl    string = "invalid-calendar-url"
+	CalendarNotFound      string = "calendar-not-found"
+	CalendarReadOnly      string = "calendar-read-only"
+	CalendarWriteFailed   string = "calendar-write-failed"
+	CalendarNeedsReauth   string = "calendar-needs-reauth"
+	MeetingsNotSupported  string = "meetings-not-supported"
+	UnknownMeetProvider   string = "unknown-meet-provider"
+	InvalidMeetingLink    string = "invalid-meeting-link"
+	InvalidRecurrence     string = "invalid-recurrence"
+	EventNotRecurring     string = "event-not-recurring"
+	EventUpdateConflict   string = "event-update-conflict"
+	InvalidTimeZone       string = "invalid-time-zone"
+	InvalidRole           string = "invalid-role"
+	CollaboratorIsOwner   string = "collaborator-is-owner"
+	InvalidEditToken      string = "invalid-edit-token"
+	EventAlreadyOwned     string = "event-already-owned"
+	EventClosed           string = "event-closed"
+	ResponsesLocked       string = "responses-locked"
+	InvalidComment        string = "invalid-comment"
+	InvalidSlot           string = "invalid-slot"
+	CommentNotFound       string = "comment-not-found"
+	InvalidQuestions      string = "invalid-questions"
+	InvalidAnswers        string = "invalid-answers"
+	InvalidPoll           string = "invalid-poll"
+	PollNotFound          string = "poll-not-found"
+	InvalidVote           string = "invalid-vote"
 )
 
 type GoogleAPIError struct {
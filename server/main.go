@@ -20,6 +20,7 @@ import (
 	"schej.it/server/db"
 	"schej.it/server/logger"
 	"schej.it/server/routes"
+	"schej.it/server/services/eventstream"
 	"schej.it/server/services/gcloud"
 	"schej.it/server/slackbot"
 	"schej.it/server/utils"
@@ -91,7 +92,7 @@ func main() {
 		AllowOrigins:     []string{"https://verdant-coyote-455921-h1.web.app", "http://localhost:8080", "http://localhost:3000", "http://192.168.1.159:8080"}, // Original
 		// AllowAllOrigins:  true, // Allows any origin for debugging - REVERTED
 		AllowMethods:     []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With", "Cookie"},
+		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With", "Cookie", "X-Edit-Token"},
 		ExposeHeaders:    []string{"Content-Length", "Content-Type", "Set-Cookie"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -101,6 +102,10 @@ func main() {
 	closeConnection := db.Init()
 	defer closeConnection()
 
+	// Deliver live updates published by every server instance
+	stopEventStream := eventstream.Init()
+	defer stopEventStream()
+
 	// Init google cloud stuff
 	closeTasks := gcloud.InitTasks()
 	defer closeTasks()
@@ -149,6 +154,8 @@ func main() {
 	routes.InitEvents(apiRouter)
 	routes.InitUsers(apiRouter)
 	routes.InitAnalytics(apiRouter)
+	routes.InitCalendarFeed(apiRouter)
+	routes.InitTasks(apiRouter)
 	slackbot.InitSlackbot(apiRouter)
 
 	// Add a base URL health check endpoint
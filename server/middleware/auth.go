@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"os"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -35,3 +37,17 @@ func AuthRequired() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// Only lets through requests from Cloud Tasks, which send the TASKS_SECRET in the X-Tasks-Secret header
+func TasksAuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("TASKS_SECRET")
+		if len(secret) == 0 || subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Tasks-Secret")), []byte(secret)) != 1 {
+			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
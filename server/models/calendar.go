@@ -11,6 +11,8 @@ const (
 	AppleCalendarType   CalendarType = "apple"
 	GoogleCalendarType  CalendarType = "google"
 	OutlookCalendarType CalendarType = "outlook"
+	ICSCalendarType     CalendarType = "ics"
+	CalDAVCalendarType  CalendarType = "caldav"
 )
 
 // OAuth2CalendarAuth contains necessary auth info for the user's google calendar account
@@ -27,11 +29,26 @@ type AppleCalendarAuth struct {
 	Password string `json:"-" bson:"password,omitempty"`
 }
 
+// CalDAVCalendarAuth contains necessary auth info for the user's account on an arbitrary CalDAV server
+type CalDAVCalendarAuth struct {
+	ServerUrl string `json:"-" bson:"serverUrl,omitempty"`
+	Username  string `json:"-" bson:"username,omitempty"`
+	Password  string `json:"-" bson:"password,omitempty"`
+}
+
+// ICSCalendarAuth contains the url of a calendar that is only available as an .ics feed.
+// The url is encrypted because secret feed urls grant read access to the whole calendar
+type ICSCalendarAuth struct {
+	Url string `json:"-" bson:"url,omitempty"`
+}
+
 // CalendarAccount contains info about the user's other signed in calendar accounts
 type CalendarAccount struct {
 	CalendarType       CalendarType        `json:"calendarType" bson:"calendarType,omitempty"`
 	OAuth2CalendarAuth *OAuth2CalendarAuth `json:"oAuth2CalendarAuth" bson:"oAuth2CalendarAuth,omitempty"`
 	AppleCalendarAuth  *AppleCalendarAuth  `json:"appleCalendarAuth" bson:"appleCalendarAuth,omitempty"`
+	ICSCalendarAuth    *ICSCalendarAuth    `json:"icsCalendarAuth" bson:"icsCalendarAuth,omitempty"`
+	CalDAVCalendarAuth *CalDAVCalendarAuth `json:"calDAVCalendarAuth" bson:"calDAVCalendarAuth,omitempty"`
 
 	Email        string                  `json:"email" bson:"email"` // Email is required for all calendar accounts
 	Picture      string                  `json:"picture" bson:"picture,omitempty"`
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -12,6 +14,24 @@ const (
 	GROUP          EventType = "group"
 )
 
+// The service that an event's meeting link is from
+type ConferencingProvider string
+
+const (
+	GoogleMeetProvider ConferencingProvider = "google_meet"
+	TeamsProvider      ConferencingProvider = "teams"
+	JitsiProvider      ConferencingProvider = "jitsi"
+	CustomLinkProvider ConferencingProvider = "custom"
+)
+
+// A calendar event that a meeting was created as, kept so that the meeting can be removed along with the link
+type ConferenceCalendarEvent struct {
+	// The user whose calendar account the event is in
+	UserId             primitive.ObjectID `json:"-" bson:"userId"`
+	CalendarAccountKey string             `json:"-" bson:"calendarAccountKey"`
+	EventId            string             `json:"-" bson:"eventId"`
+}
+
 // A response object containing an array of times that the given user is available
 type Response struct {
 	// Guest information
@@ -23,8 +43,8 @@ type Response struct {
 	User   *User              `json:"user" bson:",omitempty"`
 
 	// Availability
-	Availability []primitive.DateTime `json:"availability" bson:"availability"`
-	IfNeeded     []primitive.DateTime `json:"ifNeeded" bson:"ifNeeded"`
+	Availability SlotSet `json:"availability" bson:"availability"`
+	IfNeeded     SlotSet `json:"ifNeeded" bson:"ifNeeded"`
 
 	// Mapping from the start date of a day to the available times for that day
 	ManualAvailability *map[primitive.DateTime][]primitive.DateTime `json:"manualAvailability" bson:"manualAvailability,omitempty"`
@@ -33,6 +53,12 @@ type Response struct {
 	UseCalendarAvailability *bool                `json:"useCalendarAvailability" bson:"useCalendarAvailability,omitempty"`
 	EnabledCalendars        *map[string][]string `json:"enabledCalendars" bson:"enabledCalendars,omitempty"` // Maps email to an array of sub calendar ids
 	CalendarOptions         *CalendarOptions     `json:"calendarOptions" bson:"calendarOptions,omitempty"`
+
+	// IANA time zone that the respondent filled out their availability in
+	TimeZone *string `json:"timeZone" bson:"timeZone,omitempty"`
+
+	// Answers to the custom questions of the event
+	Answers Answers `json:"answers" bson:"answers,omitempty"`
 }
 
 // Object containing information associated with the remindee
@@ -47,6 +73,51 @@ type Attendee struct {
 	Declined *bool  `json:"declined" bson:"declined,omitempty"`
 }
 
+// What a collaborator is allowed to do with an event. Each role can do everything the roles below it can
+type CollaboratorRole string
+
+const (
+	// Can manage collaborators and delete the event, like the user who created it
+	OwnerRole CollaboratorRole = "owner"
+	// Can edit, schedule, and duplicate the event, and manage its responses and meeting link
+	EditorRole CollaboratorRole = "editor"
+	// Can see responses that are hidden by blind availability
+	ViewerRole CollaboratorRole = "viewer"
+)
+
+var collaboratorRoleRanks = map[CollaboratorRole]int{
+	ViewerRole: 1,
+	EditorRole: 2,
+	OwnerRole:  3,
+}
+
+// Whether the role is one of the collaborator roles
+func (r CollaboratorRole) IsValid() bool {
+	_, ok := collaboratorRoleRanks[r]
+	return ok
+}
+
+// Whether the role allows everything the given role allows
+func (r CollaboratorRole) Includes(role CollaboratorRole) bool {
+	return r.IsValid() && collaboratorRoleRanks[r] >= collaboratorRoleRanks[role]
+}
+
+// A user other than the creator who can manage the event
+type Collaborator struct {
+	UserId primitive.ObjectID `json:"userId" bson:"userId"`
+	Role   CollaboratorRole   `json:"role" bson:"role"`
+	User   *User              `json:"user" bson:"-"`
+}
+
+// The date range that a recurring meeting is polled for (e.g. a semester), only used for DOW events
+type Recurrence struct {
+	StartDate primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate" bson:"endDate"`
+
+	// Number of weeks between meetings, e.g. 2 for a meeting every other week. Defaults to 1
+	Interval int `json:"interval" bson:"interval,omitempty"`
+}
+
 type SignUpBlock struct {
 	Id        primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
 	Name      string              `json:"name" bson:"name,omitempty"`
@@ -66,18 +137,28 @@ type SignUpResponse struct {
 	// User information
 	UserId primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
 	User   *User              `json:"user" bson:",omitempty"`
+
+	// Answers to the custom questions of the event
+	Answers Answers `json:"answers" bson:"answers,omitempty"`
 }
 
+// A response to an event, stored in its own collection keyed by (eventId, userId)
 type EventResponse struct {
-	UserId   string    `json:"userId" bson:"userId"`
-	Response *Response `json:"response" bson:"response"`
+	Id       primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	EventId  primitive.ObjectID `json:"-" bson:"eventId,omitempty"`
+	UserId   string             `json:"userId" bson:"userId"`
+	Response *Response          `json:"response" bson:"response"`
 }
 
+// How long deleted events are kept in the trash before they are purged
+const EventRetentionPeriod = 30 * 24 * time.Hour
+
 // Representation of an Event in the mongoDB database
 type Event struct {
 	Id          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
 	ShortId     *string            `json:"shortId" bson:"shortId,omitempty"`
 	OwnerId     primitive.ObjectID `json:"ownerId" bson:"ownerId,omitempty"`
+	EditToken   *string            `json:"-" bson:"editToken,omitempty"` // Secret that lets the guest who created the event manage it
 	Name        string             `json:"name" bson:"name,omitempty"`
 	Description *string            `json:"description" bson:"description,omitempty"`
 
@@ -88,13 +169,28 @@ type Event struct {
 	When2meetHref            *string              `json:"when2meetHref" bson:"when2meetHref,omitempty"`
 	CollectEmails            *bool                `json:"collectEmails" bson:"collectEmails,omitempty"`
 
-	// Google Meet integration
-	MeetLink      *string `json:"meetLink" bson:"meetLink,omitempty"`
-	MeetStartTime *string `json:"meetStartTime" bson:"meetStartTime,omitempty"`
-	MeetEndTime   *string `json:"meetEndTime" bson:"meetEndTime,omitempty"`
+	// Custom questions that respondents answer along with their response
+	Questions *[]Question `json:"questions" bson:"questions,omitempty"`
+
+	// Polls about things other than the time, e.g. where to meet
+	Polls *[]Poll `json:"polls" bson:"polls,omitempty"`
+
+	// Video conferencing link
+	MeetLink      *string               `json:"meetLink" bson:"meetLink,omitempty"`
+	MeetStartTime *string               `json:"meetStartTime" bson:"meetStartTime,omitempty"`
+	MeetEndTime   *string               `json:"meetEndTime" bson:"meetEndTime,omitempty"`
+	MeetProvider  *ConferencingProvider `json:"meetProvider" bson:"meetProvider,omitempty"`
+	// The calendar event that Google Meet and Teams meetings were created as
+	MeetCalendarEvent *ConferenceCalendarEvent `json:"-" bson:"meetCalendarEvent,omitempty"`
 
 	Type EventType `json:"type" bson:"type,omitempty"`
 
+	// Users other than the owner who can manage the event
+	Collaborators *[]Collaborator `json:"collaborators" bson:"collaborators,omitempty"`
+
+	// Incremented every time the event is updated, used to detect concurrent updates
+	Version int `json:"version" bson:"version"`
+
 	// Sign up form details
 	IsSignUpForm    *bool                      `json:"isSignUpForm" bson:"isSignUpForm,omitempty"`
 	SignUpBlocks    *[]SignUpBlock             `json:"signUpBlocks" bson:"signUpBlocks,omitempty"`
@@ -109,8 +205,27 @@ type Event struct {
 	// Whether to only poll for days, not times
 	DaysOnly *bool `json:"daysOnly" bson:"daysOnly,omitempty"`
 
-	// Availability responses - new format for indexed queries
-	ResponsesList []EventResponse `json:"-" bson:"responses"`
+	// IANA time zone that the event was created in (e.g. "America/New_York"). DOW and days only dates
+	// are wall clock times in this time zone, so it is used to keep them at the same local time across DST transitions
+	TimeZone *string `json:"timeZone" bson:"timeZone,omitempty"`
+
+	// If set, the DOW event is a poll for a recurring meeting over this date range
+	Recurrence *Recurrence `json:"recurrence" bson:"recurrence,omitempty"`
+
+	// Responses are no longer accepted after this time
+	ClosesAt *primitive.DateTime `json:"closesAt" bson:"closesAt"`
+	// Whether the reminders were cancelled and the owner was sent a summary after the event closed
+	Closed bool `json:"-" bson:"closed"`
+
+	// When the event was moved to the trash. Deleted events can be restored until they are purged after EventRetentionPeriod
+	DeletedAt *primitive.DateTime `json:"deletedAt" bson:"deletedAt,omitempty"`
+
+	// Whether responses are read-only, except for the respondents in UnlockedRespondents (keys of the responses map)
+	ResponsesLocked     *bool     `json:"responsesLocked" bson:"responsesLocked,omitempty"`
+	UnlockedRespondents *[]string `json:"unlockedRespondents" bson:"unlockedRespondents,omitempty"`
+
+	// Availability responses, loaded from the event responses collection
+	ResponsesList []EventResponse `json:"-" bson:"-"`
 	// Availability responses - old format for backward compatibility
 	ResponsesMap map[string]*Response `json:"responses" bson:"responsesMap"`
 
@@ -118,6 +233,17 @@ type Event struct {
 	ScheduledEvent  *CalendarEvent `json:"scheduledEvent" bson:"scheduledEvent,omitempty"`
 	CalendarEventId string         `json:"calendarEventId" bson:"calendarEventId,omitempty"`
 
+	// The calendar that the scheduled event was written to, and the owner or collaborator it belongs to
+	CalendarAccountKey string             `json:"-" bson:"calendarAccountKey,omitempty"`
+	CalendarId         string             `json:"-" bson:"calendarId,omitempty"`
+	CalendarUserId     primitive.ObjectID `json:"-" bson:"calendarUserId,omitempty"`
+
+	// Ids (keys of the responses map) of the respondents expected to attend the scheduled event
+	ExpectedRespondents *[]string `json:"expectedRespondents" bson:"expectedRespondents,omitempty"`
+
+	// Incremented every time the scheduled event changes, used as the iCalendar SEQUENCE
+	ScheduleSequence int `json:"-" bson:"scheduleSequence,omitempty"`
+
 	// Remindees
 	Remindees *[]Remindee `json:"remindees" bson:"remindees,omitempty"`
 
@@ -125,6 +251,75 @@ type Event struct {
 	Attendees *[]Attendee `json:"attendees" bson:"attendees,omitempty"`
 }
 
+// Returns the role of the given user on the event. The user who created the event is always an owner.
+// Returns an empty role if the user isn't the owner or a collaborator
+func (e *Event) GetRole(userId primitive.ObjectID) CollaboratorRole {
+	if userId.IsZero() {
+		return ""
+	}
+	if e.OwnerId == userId {
+		return OwnerRole
+	}
+	if e.Collaborators != nil {
+		for _, collaborator := range *e.Collaborators {
+			if collaborator.UserId == userId {
+				return collaborator.Role
+			}
+		}
+	}
+
+	return ""
+}
+
+// Whether the given user has at least the given role on the event
+func (e *Event) HasRole(userId primitive.ObjectID, role CollaboratorRole) bool {
+	return e.GetRole(userId).Includes(role)
+}
+
+// Whether the event no longer accepts responses
+func (e *Event) IsClosed() bool {
+	return e.ClosesAt != nil && !time.Now().Before(e.ClosesAt.Time())
+}
+
+// Whether the response of the given respondent (key of the responses map) is read-only
+func (e *Event) IsResponseLocked(userId string) bool {
+	if e.ResponsesLocked == nil || !*e.ResponsesLocked {
+		return false
+	}
+	if e.UnlockedRespondents != nil {
+		for _, unlockedUserId := range *e.UnlockedRespondents {
+			if unlockedUserId == userId {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Whether the given time is the start of a slot that respondents can mark themselves available for
+func (e *Event) HasSlot(slotStart primitive.DateTime) bool {
+	var dayLength time.Duration
+	if e.Duration != nil {
+		dayLength = time.Duration(float64(*e.Duration) * float64(time.Hour))
+	}
+	for _, date := range e.Dates {
+		if e.DaysOnly != nil && *e.DaysOnly {
+			if slotStart == date {
+				return true
+			}
+			continue
+		}
+
+		offset := slotStart.Time().Sub(date.Time())
+		if offset >= 0 && offset < dayLength && offset%SlotDuration == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (e *Event) GetId() string {
 	if e.ShortId != nil {
 		return *e.ShortId
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Maximum number of characters in a comment
+const MaxCommentLength = 2000
+
+// A comment left on an event, stored in its own collection
+type EventComment struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId   primitive.ObjectID `json:"-" bson:"eventId"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+
+	// Who wrote the comment. The user id is empty for guests, and the name is the name they used at the time
+	UserId primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
+	Name   string             `json:"name" bson:"name"`
+
+	Text string `json:"text" bson:"text"`
+
+	// The start of the time slot the comment is about. Comments without a slot are about the event as a whole
+	SlotStart *primitive.DateTime `json:"slotStart,omitempty" bson:"slotStart,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A kind of change to an event that is recorded in its history
+type EventHistoryAction string
+
+const (
+	EventEditedAction          EventHistoryAction = "eventEdited"
+	EventScheduledAction       EventHistoryAction = "eventScheduled"
+	EventUnscheduledAction     EventHistoryAction = "eventUnscheduled"
+	EventDeletedAction         EventHistoryAction = "eventDeleted"
+	EventRestoredAction        EventHistoryAction = "eventRestored"
+	ResponseAddedAction        EventHistoryAction = "responseAdded"
+	ResponseUpdatedAction      EventHistoryAction = "responseUpdated"
+	ResponseDeletedAction      EventHistoryAction = "responseDeleted"
+	ResponsesLockedAction      EventHistoryAction = "responsesLocked"
+	ResponsesUnlockedAction    EventHistoryAction = "responsesUnlocked"
+	AttendeeAddedAction        EventHistoryAction = "attendeeAdded"
+	AttendeeRemovedAction      EventHistoryAction = "attendeeRemoved"
+	CollaboratorUpdatedAction  EventHistoryAction = "collaboratorUpdated"
+	CollaboratorRemovedAction  EventHistoryAction = "collaboratorRemoved"
+	OwnershipTransferredAction EventHistoryAction = "ownershipTransferred"
+	PollAddedAction            EventHistoryAction = "pollAdded"
+	PollDeletedAction          EventHistoryAction = "pollDeleted"
+)
+
+// A single change in the append only history of an event, stored in its own collection
+type EventHistoryEntry struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId   primitive.ObjectID `json:"-" bson:"eventId"`
+	Timestamp primitive.DateTime `json:"timestamp" bson:"timestamp"`
+	Action    EventHistoryAction `json:"action" bson:"action"`
+
+	// Who made the change. The user id is empty for guests, and the name is the name they used at the time
+	ActorId   primitive.ObjectID `json:"actorId" bson:"actorId,omitempty"`
+	ActorName string             `json:"actorName" bson:"actorName,omitempty"`
+
+	// What the change was made to, e.g. the key of a response, the email of an attendee, or the id of a collaborator
+	Target string `json:"target,omitempty" bson:"target,omitempty"`
+
+	// The fields that were changed by an edit
+	Fields []string `json:"fields,omitempty" bson:"fields,omitempty"`
+}
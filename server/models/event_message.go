@@ -0,0 +1,17 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A live update to an event, stored so that every server instance can deliver it to the clients streaming the event.
+// Messages are only kept briefly, since they are delivered as soon as they are inserted
+type EventMessage struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty"`
+	EventId   string             `bson:"eventId"`
+	CreatedAt primitive.DateTime `bson:"createdAt"`
+
+	// The type and user id of the eventstream message
+	Type   string `bson:"type"`
+	UserId string `bson:"userId,omitempty"`
+}
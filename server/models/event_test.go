@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEventRoles(t *testing.T) {
+	owner, editor, viewer, stranger := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	event := Event{
+		OwnerId: owner,
+		Collaborators: &[]Collaborator{
+			{UserId: editor, Role: EditorRole},
+			{UserId: viewer, Role: ViewerRole},
+		},
+	}
+
+	tests := []struct {
+		userId   primitive.ObjectID
+		role     CollaboratorRole
+		expected bool
+	}{
+		{owner, OwnerRole, true},
+		{editor, OwnerRole, false},
+		{editor, EditorRole, true},
+		{editor, ViewerRole, true},
+		{viewer, EditorRole, false},
+		{viewer, ViewerRole, true},
+		{stranger, ViewerRole, false},
+		{primitive.NilObjectID, ViewerRole, false},
+	}
+	for _, test := range tests {
+		if actual := event.HasRole(test.userId, test.role); actual != test.expected {
+			t.Errorf("expected HasRole(%v, %s) to be %v", test.userId, test.role, test.expected)
+		}
+	}
+
+	// Events without an owner don't give everyone a role
+	if (&Event{}).HasRole(primitive.NilObjectID, ViewerRole) {
+		t.Error("expected nobody to have a role on an event without an owner")
+	}
+}
+
+func TestEventHasSlot(t *testing.T) {
+	duration := float32(2)
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	event := Event{Duration: &duration, Dates: []primitive.DateTime{primitive.NewDateTimeFromTime(start)}}
+
+	tests := []struct {
+		slotStart time.Time
+		expected  bool
+	}{
+		{start, true},
+		{start.Add(105 * time.Minute), true},
+		{start.Add(2 * time.Hour), false},
+		{start.Add(10 * time.Minute), false},
+		{start.Add(-SlotDuration), false},
+	}
+	for _, test := range tests {
+		if actual := event.HasSlot(primitive.NewDateTimeFromTime(test.slotStart)); actual != test.expected {
+			t.Errorf("expected HasSlot(%v) to be %v, got %v", test.slotStart, test.expected, actual)
+		}
+	}
+
+	daysOnly := true
+	event.DaysOnly = &daysOnly
+	if event.HasSlot(primitive.NewDateTimeFromTime(start.Add(time.Hour))) {
+		t.Error("expected only the dates themselves to be slots of days only events")
+	}
+}
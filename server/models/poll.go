@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How respondents vote on a poll
+type PollType string
+
+const (
+	// Respondents choose one option
+	SingleChoicePoll PollType = "singleChoice"
+	// Respondents choose any number of options
+	MultipleChoicePoll PollType = "multipleChoice"
+	// Respondents rank some or all of the options from most to least preferred
+	RankedPoll PollType = "ranked"
+)
+
+// A question other than when to meet that is attached to an event, e.g. where to meet
+type Poll struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id"`
+	Question string             `json:"question" bson:"question"`
+	Type     PollType           `json:"type" bson:"type"`
+	Options  []PollOption       `json:"options" bson:"options"`
+}
+
+type PollOption struct {
+	Id   primitive.ObjectID `json:"_id" bson:"_id"`
+	Text string             `json:"text" bson:"text"`
+}
+
+// The vote of a respondent on a poll, stored in its own collection keyed by (eventId, pollId, userId).
+// Duplicated events keep the ids of their polls, so the poll id alone doesn't identify the poll
+type PollVote struct {
+	Id      primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	EventId primitive.ObjectID `json:"-" bson:"eventId"`
+	PollId  primitive.ObjectID `json:"pollId" bson:"pollId"`
+
+	// The user id of signed in respondents, or the name of guests, like the keys of the responses map
+	UserId string `json:"userId" bson:"userId"`
+	// The name of the respondent at the time they voted
+	Name string `json:"name" bson:"name"`
+
+	// The chosen options. For ranked polls, in order from most to least preferred
+	OptionIds []primitive.ObjectID `json:"optionIds" bson:"optionIds"`
+}
+
+// Whether the poll has a question and at least two distinct non empty options
+func (p *Poll) IsValid() bool {
+	switch p.Type {
+	case SingleChoicePoll, MultipleChoicePoll, RankedPoll:
+	default:
+		return false
+	}
+	if len(strings.TrimSpace(p.Question)) == 0 || len(p.Options) < 2 {
+		return false
+	}
+
+	texts := make(Set[string])
+	for _, option := range p.Options {
+		if _, ok := texts[option.Text]; ok || len(strings.TrimSpace(option.Text)) == 0 {
+			return false
+		}
+		texts[option.Text] = struct{}{}
+	}
+
+	return true
+}
+
+// Whether the chosen options are distinct options of the poll, and there is exactly one of them for single choice polls
+func (p *Poll) IsValidVote(optionIds []primitive.ObjectID) bool {
+	if len(optionIds) == 0 || (p.Type == SingleChoicePoll && len(optionIds) > 1) {
+		return false
+	}
+
+	options := make(Set[primitive.ObjectID])
+	for _, option := range p.Options {
+		options[option.Id] = struct{}{}
+	}
+	chosen := make(Set[primitive.ObjectID])
+	for _, optionId := range optionIds {
+		if _, ok := options[optionId]; !ok {
+			return false
+		}
+		if _, ok := chosen[optionId]; ok {
+			return false
+		}
+		chosen[optionId] = struct{}{}
+	}
+
+	return true
+}
+
+// Returns the poll with the given id, or nil if the event doesn't have it
+func (e *Event) GetPoll(pollId string) *Poll {
+	if e.Polls == nil {
+		return nil
+	}
+	for i := range *e.Polls {
+		if (*e.Polls)[i].Id.Hex() == pollId {
+			return &(*e.Polls)[i]
+		}
+	}
+
+	return nil
+}
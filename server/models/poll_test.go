@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPollIsValidVote(t *testing.T) {
+	park, cafe := primitive.NewObjectID(), primitive.NewObjectID()
+	poll := Poll{Options: []PollOption{{Id: park, Text: "Park"}, {Id: cafe, Text: "Cafe"}}}
+
+	tests := []struct {
+		pollType  PollType
+		optionIds []primitive.ObjectID
+		expected  bool
+	}{
+		{SingleChoicePoll, []primitive.ObjectID{park}, true},
+		{SingleChoicePoll, []primitive.ObjectID{park, cafe}, false},
+		{MultipleChoicePoll, []primitive.ObjectID{park, cafe}, true},
+		{MultipleChoicePoll, []primitive.ObjectID{}, false},
+		{RankedPoll, []primitive.ObjectID{cafe}, true},
+		{RankedPoll, []primitive.ObjectID{cafe, cafe}, false},
+		{RankedPoll, []primitive.ObjectID{primitive.NewObjectID()}, false},
+	}
+	for i, test := range tests {
+		poll.Type = test.pollType
+		if actual := poll.IsValidVote(test.optionIds); actual != test.expected {
+			t.Errorf("vote %d: expected %v, got %v", i, test.expected, actual)
+		}
+	}
+}
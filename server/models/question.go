@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The kind of answer a custom question asks for
+type QuestionType string
+
+const (
+	ShortTextQuestion      QuestionType = "shortText"
+	SingleChoiceQuestion   QuestionType = "singleChoice"
+	MultipleChoiceQuestion QuestionType = "multipleChoice"
+)
+
+// Maximum number of characters in the answer to a short text question
+const MaxAnswerLength = 500
+
+// A question that the owner asks respondents in addition to their availability, e.g. their T-shirt size
+type Question struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Type     QuestionType       `json:"type" bson:"type"`
+	Prompt   string             `json:"prompt" bson:"prompt"`
+	Options  []string           `json:"options" bson:"options,omitempty"` // The choices for choice questions
+	Required bool               `json:"required" bson:"required"`
+}
+
+// Answers to the custom questions of an event, mapping the id of each question to the text answer or the chosen options
+type Answers map[string][]string
+
+// Whether the question has a prompt, and choice questions have distinct non empty options
+func (q *Question) IsValid() bool {
+	if len(strings.TrimSpace(q.Prompt)) == 0 {
+		return false
+	}
+
+	switch q.Type {
+	case ShortTextQuestion:
+		return len(q.Options) == 0
+	case SingleChoiceQuestion, MultipleChoiceQuestion:
+		if len(q.Options) == 0 {
+			return false
+		}
+		seen := make(Set[string])
+		for _, option := range q.Options {
+			if _, ok := seen[option]; ok || len(strings.TrimSpace(option)) == 0 {
+				return false
+			}
+			seen[option] = struct{}{}
+		}
+		return true
+	}
+
+	return false
+}
+
+// Whether the answers only answer questions of the event, with valid answers, and answer every required question
+func (e *Event) AreValidAnswers(answers Answers) bool {
+	questions := make(map[string]Question)
+	if e.Questions != nil {
+		for _, question := range *e.Questions {
+			questions[question.Id.Hex()] = question
+		}
+	}
+
+	for questionId, answer := range answers {
+		question, ok := questions[questionId]
+		if !ok || !question.isValidAnswer(answer) {
+			return false
+		}
+	}
+	for questionId, question := range questions {
+		if question.Required && !hasAnswer(answers[questionId]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (q *Question) isValidAnswer(answer []string) bool {
+	switch q.Type {
+	case ShortTextQuestion:
+		return len(answer) <= 1 && (len(answer) == 0 || utf8.RuneCountInString(answer[0]) <= MaxAnswerLength)
+	case SingleChoiceQuestion:
+		if len(answer) > 1 {
+			return false
+		}
+	}
+
+	options := make(Set[string])
+	for _, option := range q.Options {
+		options[option] = struct{}{}
+	}
+	chosen := make(Set[string])
+	for _, option := range answer {
+		if _, ok := options[option]; !ok {
+			return false
+		}
+		if _, ok := chosen[option]; ok {
+			return false
+		}
+		chosen[option] = struct{}{}
+	}
+
+	return true
+}
+
+func hasAnswer(answer []string) bool {
+	for _, value := range answer {
+		if len(strings.TrimSpace(value)) > 0 {
+			return true
+		}
+	}
+	return false
+}
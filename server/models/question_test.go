@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAreValidAnswers(t *testing.T) {
+	dietId, sizeId, topicsId := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	event := Event{Questions: &[]Question{
+		{Id: dietId, Type: ShortTextQuestion, Prompt: "Dietary restrictions"},
+		{Id: sizeId, Type: SingleChoiceQuestion, Prompt: "T-shirt size", Options: []string{"S", "M", "L"}, Required: true},
+		{Id: topicsId, Type: MultipleChoiceQuestion, Prompt: "Topics", Options: []string{"Go", "Vue"}},
+	}}
+
+	tests := []struct {
+		name     string
+		answers  Answers
+		expected bool
+	}{
+		{"required only", Answers{sizeId.Hex(): {"M"}}, true},
+		{"all questions", Answers{dietId.Hex(): {"Vegan"}, sizeId.Hex(): {"L"}, topicsId.Hex(): {"Go", "Vue"}}, true},
+		{"missing required", Answers{dietId.Hex(): {"Vegan"}}, false},
+		{"blank required", Answers{sizeId.Hex(): {" "}}, false},
+		{"unknown option", Answers{sizeId.Hex(): {"XL"}}, false},
+		{"several single choices", Answers{sizeId.Hex(): {"S", "M"}}, false},
+		{"repeated choice", Answers{sizeId.Hex(): {"S"}, topicsId.Hex(): {"Go", "Go"}}, false},
+		{"unknown question", Answers{sizeId.Hex(): {"S"}, primitive.NewObjectID().Hex(): {"?"}}, false},
+	}
+	for _, test := range tests {
+		if actual := event.AreValidAnswers(test.answers); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestQuestionIsValid(t *testing.T) {
+	tests := []struct {
+		question Question
+		expected bool
+	}{
+		{Question{Type: ShortTextQuestion, Prompt: "Dietary restrictions"}, true},
+		{Question{Type: ShortTextQuestion, Prompt: " "}, false},
+		{Question{Type: ShortTextQuestion, Prompt: "Name", Options: []string{"A"}}, false},
+		{Question{Type: SingleChoiceQuestion, Prompt: "T-shirt size", Options: []string{"S", "M"}}, true},
+		{Question{Type: SingleChoiceQuestion, Prompt: "T-shirt size"}, false},
+		{Question{Type: MultipleChoiceQuestion, Prompt: "Topics", Options: []string{"Go", "Go"}}, false},
+		{Question{Type: "dropdown", Prompt: "Topics", Options: []string{"Go"}}, false},
+	}
+	for i, test := range tests {
+		if actual := test.question.IsValid(); actual != test.expected {
+			t.Errorf("question %d: expected %v, got %v", i, test.expected, actual)
+		}
+	}
+}
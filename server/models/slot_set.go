@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The granularity of availability, i.e. the length of a single slot
+const SlotDuration = 15 * time.Minute
+
+// A sorted list of the start times of the slots someone is available for. It's an array of timestamps in JSON,
+// but is stored in the database as intervals of consecutive slots to keep event documents small
+type SlotSet []primitive.DateTime
+
+// A range of consecutive slots, with the end being exclusive
+type slotInterval struct {
+	Start primitive.DateTime `bson:"s"`
+	End   primitive.DateTime `bson:"e"`
+}
+
+// Returns a slot set containing the given timestamps, sorted and without duplicates
+func NewSlotSet(timestamps []primitive.DateTime) SlotSet {
+	if timestamps == nil {
+		return nil
+	}
+
+	slots := make(SlotSet, len(timestamps))
+	copy(slots, timestamps)
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+
+	deduped := slots[:0]
+	for i, slot := range slots {
+		if i > 0 && slot == slots[i-1] {
+			continue
+		}
+		deduped = append(deduped, slot)
+	}
+	return deduped
+}
+
+// Returns the slots between timeMin and timeMax (inclusive) in O(log n)
+func (s SlotSet) Between(timeMin time.Time, timeMax time.Time) SlotSet {
+	first, last := primitive.NewDateTimeFromTime(timeMin), primitive.NewDateTimeFromTime(timeMax)
+	start := sort.Search(len(s), func(i int) bool { return s[i] >= first })
+	end := sort.Search(len(s), func(i int) bool { return s[i] > last })
+	if start >= end {
+		return make(SlotSet, 0)
+	}
+
+	subset := make(SlotSet, end-start)
+	copy(subset, s[start:end])
+	return subset
+}
+
+func (s SlotSet) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if s == nil {
+		return bson.TypeNull, nil, nil
+	}
+
+	intervals := make([]slotInterval, 0)
+	step := primitive.DateTime(SlotDuration.Milliseconds())
+	for _, slot := range NewSlotSet(s) {
+		if last := len(intervals) - 1; last >= 0 && intervals[last].End == slot {
+			intervals[last].End = slot + step
+			continue
+		}
+		intervals = append(intervals, slotInterval{Start: slot, End: slot + step})
+	}
+
+	return bson.MarshalValue(intervals)
+}
+
+// Decodes both the interval format and the old format of an array of timestamps
+func (s *SlotSet) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bson.TypeNull, bson.TypeUndefined:
+		*s = nil
+		return nil
+	case bson.TypeArray:
+	default:
+		return fmt.Errorf("cannot decode %v into a SlotSet", t)
+	}
+
+	values, err := bson.Raw(data).Values()
+	if err != nil {
+		return err
+	}
+
+	slots := make(SlotSet, 0, len(values))
+	step := primitive.DateTime(SlotDuration.Milliseconds())
+	for _, value := range values {
+		switch value.Type {
+		case bson.TypeDateTime:
+			slots = append(slots, primitive.DateTime(value.DateTime()))
+		case bson.TypeEmbeddedDocument:
+			var interval slotInterval
+			if err := value.Unmarshal(&interval); err != nil {
+				return err
+			}
+			for slot := interval.Start; slot < interval.End; slot += step {
+				slots = append(slots, slot)
+			}
+		default:
+			return fmt.Errorf("cannot decode %v into a slot", value.Type)
+		}
+	}
+
+	*s = NewSlotSet(slots)
+	return nil
+}
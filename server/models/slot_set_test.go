@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSlotSetBSON(t *testing.T) {
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	slots := NewSlotSet([]primitive.DateTime{
+		primitive.NewDateTimeFromTime(start.Add(2 * time.Hour)),
+		primitive.NewDateTimeFromTime(start),
+		primitive.NewDateTimeFromTime(start.Add(SlotDuration)),
+		primitive.NewDateTimeFromTime(start.Add(SlotDuration)),
+		primitive.NewDateTimeFromTime(start.Add(2 * SlotDuration)),
+	})
+
+	data, err := bson.Marshal(Response{Availability: slots})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Consecutive slots are stored as a single interval
+	intervals, err := bson.Raw(data).Lookup("availability").Array().Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intervals) != 2 {
+		t.Errorf("expected 2 intervals, got %d", len(intervals))
+	}
+
+	var response Response
+	if err := bson.Unmarshal(data, &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Availability) != 4 || response.Availability[0] != primitive.NewDateTimeFromTime(start) {
+		t.Errorf("expected 4 sorted slots, got %v", response.Availability)
+	}
+	if response.IfNeeded != nil {
+		t.Errorf("expected if needed to stay nil, got %v", response.IfNeeded)
+	}
+}
+
+func TestSlotSetOldFormat(t *testing.T) {
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	data, err := bson.Marshal(bson.M{
+		"availability": bson.A{
+			primitive.NewDateTimeFromTime(start.Add(SlotDuration)),
+			primitive.NewDateTimeFromTime(start),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response Response
+	if err := bson.Unmarshal(data, &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Availability) != 2 || response.Availability[0] != primitive.NewDateTimeFromTime(start) {
+		t.Errorf("expected 2 sorted slots, got %v", response.Availability)
+	}
+
+	if between := response.Availability.Between(start.Add(time.Minute), start.Add(time.Hour)); len(between) != 1 {
+		t.Errorf("expected 1 slot in range, got %v", between)
+	}
+}
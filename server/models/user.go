@@ -30,6 +30,9 @@ type User struct {
 
 	// Calendar options
 	CalendarOptions *CalendarOptions `json:"calendarOptions" bson:"calendarOptions,omitempty"`
+
+	// Secret token used to access the user's calendar subscription feed
+	CalendarFeedToken *string `json:"-" bson:"calendarFeedToken,omitempty"`
 }
 
 // Declare the possible types of TokenOrigin
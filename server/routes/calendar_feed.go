@@ -0,0 +1,74 @@
+/* The /calendar-feed group contains the public calendar subscription feeds of each user */
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/services/ics"
+	"schej.it/server/utils"
+)
+
+func InitCalendarFeed(router *gin.RouterGroup) {
+	calendarFeedRouter := router.Group("/calendar-feed")
+	calendarFeedRouter.GET("/:token", getCalendarFeed)
+}
+
+// @Summary Gets the calendar subscription feed of the user with the given token
+// @Description Returns an iCalendar feed of every scheduled event the user owns or responded to, plus all day placeholders for pending polls if "pending" is true
+// @Tags calendar-feed
+// @Produce text/calendar
+// @Param token path string true "Calendar feed token, optionally followed by .ics"
+// @Param pending query bool false "Whether to include all day placeholders for events that haven't been scheduled yet"
+// @Success 200 {string} string "The iCalendar feed"
+// @Router /calendar-feed/{token} [get]
+func getCalendarFeed(c *gin.Context) {
+	// Bind query parameters
+	payload := struct {
+		Pending bool `form:"pending"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	user := db.GetUserByCalendarFeedToken(token)
+	if user == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cal := ics.NewFeedCalendar("schej.it")
+
+	for _, event := range db.GetEventsForUser(user) {
+		if event.ScheduledEvent != nil {
+			var organizer *ics.Participant
+			if owner := db.GetUserById(event.OwnerId.Hex()); owner != nil {
+				organizer = &ics.Participant{Name: fmt.Sprintf("%s %s", owner.FirstName, owner.LastName), Email: owner.Email}
+			}
+
+			vevent := ics.NewScheduledEvent(&event, ics.ScheduledEventOptions{Method: ics.PUBLISH, Organizer: organizer})
+			cal.Children = append(cal.Children, vevent.Component)
+		} else if payload.Pending {
+			for _, vevent := range ics.NewPendingPollEvents(&event) {
+				cal.Children = append(cal.Children, vevent.Component)
+			}
+		}
+	}
+
+	data, err := ics.Encode(cal)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// Returns the url of the calendar feed with the given token
+func getCalendarFeedUrl(token string) string {
+	return fmt.Sprintf("%s/api/calendar-feed/%s.ics", utils.GetBaseUrl(), token)
+}
@@ -0,0 +1,193 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/eventstream"
+	"schej.it/server/services/listmonk"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the comments on an event from oldest to newest
+// @Description For events with blind availability, people who can't view the responses only see their own comments
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.EventComment
+// @Router /events/{eventId}/comments [get]
+func getEventComments(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	comments := db.GetEventComments(event.Id)
+
+	// Comments can give away when people are available, so they are hidden like the responses
+	userId := getSessionUserId(c)
+	if utils.Coalesce(event.BlindAvailabilityEnabled) && !event.HasRole(userId, models.ViewerRole) && !hasEditToken(c, event) {
+		ownComments := make([]models.EventComment, 0)
+		for _, comment := range comments {
+			if userId != primitive.NilObjectID && comment.UserId == userId {
+				ownComments = append(ownComments, comment)
+			}
+		}
+		comments = ownComments
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// @Summary Adds a comment to an event, optionally about one of its time slots
+// @Description Guests have to provide the name to show with their comment. The owner is notified by email if notifications are enabled
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{text=string,name=string,slotStart=string} true "Object containing the comment, the name of the guest, and the start of the slot the comment is about"
+// @Success 201 {object} models.EventComment
+// @Router /events/{eventId}/comments [post]
+func addEventComment(c *gin.Context) {
+	payload := struct {
+		Text string `json:"text" binding:"required"`
+
+		// Name of the guest leaving the comment
+		Name string `json:"name"`
+
+		// Start of the time slot the comment is about
+		SlotStart *primitive.DateTime `json:"slotStart"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	text := strings.TrimSpace(payload.Text)
+	if len(text) == 0 || utf8.RuneCountInString(text) > models.MaxCommentLength {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidComment})
+		return
+	}
+	if payload.SlotStart != nil && !event.HasSlot(*payload.SlotStart) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidSlot})
+		return
+	}
+
+	comment := models.EventComment{
+		EventId:   event.Id,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		UserId:    getSessionUserId(c),
+		Name:      strings.TrimSpace(payload.Name),
+		Text:      text,
+		SlotStart: payload.SlotStart,
+	}
+	if comment.UserId != primitive.NilObjectID {
+		if user := db.GetUserById(comment.UserId.Hex()); user != nil {
+			comment.Name = strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+		}
+	}
+	if len(comment.Name) == 0 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidComment})
+		return
+	}
+
+	if err := db.InsertEventComment(&comment); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.CommentAdded})
+
+	// Notify the owner, the same way as when someone responds
+	if (utils.Coalesce(event.NotificationsEnabled) || event.Type == models.GROUP) && comment.UserId != event.OwnerId {
+		// Send email asynchronously
+		go func() {
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}()
+
+			owner := db.GetUserById(event.OwnerId.Hex())
+			if owner == nil {
+				return
+			}
+
+			eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+			if event.Type == models.GROUP {
+				eventUrl = fmt.Sprintf("%s/g/%s", utils.GetBaseUrl(), event.GetId())
+			}
+
+			// The template formats the slot in the owner's time zone
+			var slotStart string
+			if comment.SlotStart != nil {
+				slotStart = comment.SlotStart.Time().UTC().Format(time.RFC3339)
+			}
+
+			someoneCommentedEmailId := 18
+			listmonk.SendEmail(owner.Email, someoneCommentedEmailId, bson.M{
+				"eventName":     event.Name,
+				"ownerName":     owner.FirstName,
+				"commenterName": comment.Name,
+				"comment":       comment.Text,
+				"slotStart":     slotStart,
+				"eventUrl":      eventUrl,
+			})
+		}()
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// @Summary Deletes a comment on an event
+// @Description Signed in users can delete their own comments, and owners and editors can delete any comment
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200
+// @Router /events/{eventId}/comments/{commentId} [delete]
+func deleteEventComment(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	comment := db.GetEventComment(event.Id, c.Param("commentId"))
+	if comment == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.CommentNotFound})
+		return
+	}
+
+	userId := getSessionUserId(c)
+	isAuthor := userId != primitive.NilObjectID && comment.UserId == userId
+	if !isAuthor && !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	if err := db.DeleteEventComment(comment.Id); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.CommentDeleted})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
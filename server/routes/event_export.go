@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/utils"
+)
+
+// @Summary Downloads the respondents of an event and their answers to the custom questions as a CSV file
+// @Description Sign up forms also include the blocks each respondent signed up for. Requires at least the viewer role, or the edit token of a guest event in the X-Edit-Token header
+// @Tags events
+// @Produce text/csv
+// @Param eventId path string true "Event ID"
+// @Success 200 {string} string "The .csv file"
+// @Router /events/{eventId}/export [get]
+func exportEventResponses(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.ViewerRole) {
+		return
+	}
+
+	isSignUpForm := utils.Coalesce(event.IsSignUpForm)
+	questions := utils.Coalesce(event.Questions)
+
+	header := []string{"Name", "Email"}
+	if isSignUpForm {
+		header = append(header, "Sign up blocks")
+	}
+	for _, question := range questions {
+		header = append(header, question.Prompt)
+	}
+	rows := [][]string{header}
+
+	addRow := func(userId string, name string, email string, answers models.Answers, signUpBlocks []string) {
+		// Use the current name and email of signed in respondents
+		if user := db.GetUserById(userId); user != nil {
+			name = strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+			email = user.Email
+		} else if len(name) == 0 {
+			// User was deleted
+			return
+		}
+
+		row := []string{name, email}
+		if isSignUpForm {
+			row = append(row, strings.Join(signUpBlocks, "; "))
+		}
+		for _, question := range questions {
+			row = append(row, strings.Join(answers[question.Id.Hex()], "; "))
+		}
+		rows = append(rows, row)
+	}
+
+	if isSignUpForm {
+		blockNames := make(map[string]string)
+		for _, block := range utils.Coalesce(event.SignUpBlocks) {
+			blockNames[block.Id.Hex()] = block.Name
+		}
+
+		// Sign up responses are stored in a map, so sort them to keep the export stable
+		userIds := make([]string, 0, len(event.SignUpResponses))
+		for userId := range event.SignUpResponses {
+			userIds = append(userIds, userId)
+		}
+		sort.Strings(userIds)
+
+		for _, userId := range userIds {
+			response := event.SignUpResponses[userId]
+			if response == nil {
+				continue
+			}
+			signUpBlocks := utils.Map(response.SignUpBlockIds, func(id primitive.ObjectID) string { return blockNames[id.Hex()] })
+			addRow(userId, response.Name, response.Email, response.Answers, signUpBlocks)
+		}
+	} else {
+		for _, eventResponse := range event.ResponsesList {
+			if eventResponse.Response == nil {
+				continue
+			}
+			addRow(eventResponse.UserId, eventResponse.Response.Name, eventResponse.Response.Email, eventResponse.Response.Answers, nil)
+		}
+	}
+
+	var data bytes.Buffer
+	writer := csv.NewWriter(&data)
+	for _, row := range rows {
+		if err := writer.Write(utils.Map(row, escapeCsvFormula)); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, event.GetId()))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data.Bytes())
+}
+
+// Prefixes values that spreadsheet apps would run as formulas with a quote, since respondents can write anything
+func escapeCsvFormula(value string) string {
+	if len(value) > 0 && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -3,18 +3,20 @@ package routes
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	googlecalendar "google.golang.org/api/calendar/v3"
 	"schej.it/server/db"
 	"schej.it/server/errs"
 	"schej.it/server/logger"
@@ -22,10 +24,14 @@ import (
 	"schej.it/server/models"
 	"schej.it/server/responses"
 	"schej.it/server/services/auth"
+	"schej.it/server/services/availability"
 	"schej.it/server/services/calendar"
+	"schej.it/server/services/conferencing"
+	"schej.it/server/services/eventstream"
 	"schej.it/server/services/gcloud"
-	"schej.it/server/services/google_api"
+	"schej.it/server/services/ics"
 	"schej.it/server/services/listmonk"
+	"schej.it/server/services/microsoftgraph"
 	"schej.it/server/slackbot"
 	"schej.it/server/utils"
 )
@@ -56,22 +62,48 @@ func InitEvents(router *gin.RouterGroup) {
 	eventRouter.PUT("/:eventId", editEvent)
 	eventRouter.GET("/:eventId", getEvent)
 	eventRouter.GET("/:eventId/responses", getResponses)
+	eventRouter.GET("/:eventId/stream", streamEvent)
+	eventRouter.GET("/:eventId/best-times", getBestTimes)
+	eventRouter.GET("/:eventId/recurring-slots", middleware.AuthRequired(), getRecurringSlots)
 	eventRouter.POST("/:eventId/response", updateEventResponse)
 	eventRouter.DELETE("/:eventId/response", deleteEventResponse)
 	eventRouter.POST("/:eventId/responded", userResponded)
 	eventRouter.POST("/:eventId/decline", middleware.AuthRequired(), declineInvite)
 	eventRouter.GET("/:eventId/calendar-availabilities", middleware.AuthRequired(), getCalendarAvailabilities)
-	eventRouter.DELETE("/:eventId", middleware.AuthRequired(), deleteEvent)
+	eventRouter.DELETE("/:eventId", deleteEvent)
+	eventRouter.POST("/:eventId/restore", restoreEvent)
+	eventRouter.POST("/:eventId/claim", middleware.AuthRequired(), claimEvent)
+	eventRouter.POST("/:eventId/transfer", middleware.AuthRequired(), transferEvent)
 	eventRouter.POST("/:eventId/duplicate", middleware.AuthRequired(), duplicateEvent)
+	eventRouter.POST("/:eventId/collaborators", middleware.AuthRequired(), setCollaborator)
+	eventRouter.DELETE("/:eventId/collaborators/:userId", middleware.AuthRequired(), removeCollaborator)
+	eventRouter.POST("/:eventId/schedule", middleware.AuthRequired(), scheduleEvent)
+	eventRouter.DELETE("/:eventId/schedule", middleware.AuthRequired(), unscheduleEvent)
+	eventRouter.POST("/:eventId/lock", middleware.AuthRequired(), lockResponses)
+	eventRouter.DELETE("/:eventId/lock", middleware.AuthRequired(), unlockResponses)
+	eventRouter.GET("/:eventId/history", getEventHistory)
+	eventRouter.GET("/:eventId/comments", getEventComments)
+	eventRouter.POST("/:eventId/comments", addEventComment)
+	eventRouter.DELETE("/:eventId/comments/:commentId", deleteEventComment)
+	eventRouter.GET("/:eventId/polls", getPolls)
+	eventRouter.POST("/:eventId/polls", addPoll)
+	eventRouter.DELETE("/:eventId/polls/:pollId", deletePoll)
+	eventRouter.POST("/:eventId/polls/:pollId/vote", votePoll)
+	eventRouter.DELETE("/:eventId/polls/:pollId/vote", deletePollVote)
+	eventRouter.GET("/:eventId/ics", getEventIcs)
+	eventRouter.GET("/:eventId/export", exportEventResponses)
 	eventRouter.POST("/create-google-meet", middleware.AuthRequired(), createGoogleMeet)
+	eventRouter.POST("/create-teams-meeting", middleware.AuthRequired(), createTeamsMeeting)
+	eventRouter.POST("/:eventId/conference", middleware.AuthRequired(), createConference)
+	eventRouter.DELETE("/:eventId/conference", middleware.AuthRequired(), deleteConference)
 }
 
 // @Summary Creates a new event
 // @Tags events
 // @Accept json
 // @Produce json
-// @Param payload body object{name=string,duration=float32,dates=[]string,type=models.EventType,isSignUpForm=bool,signUpBlocks=[]models.SignUpBlock,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,when2meetHref=string,attendees=[]string} true "Object containing info about the event to create"
-// @Success 201 {object} object{eventId=string}
+// @Param payload body object{name=string,duration=float32,dates=[]string,type=models.EventType,isSignUpForm=bool,signUpBlocks=[]models.SignUpBlock,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,when2meetHref=string,timeZone=string,recurrence=models.Recurrence,attendees=[]string,closesAt=string,questions=[]models.Question} true "Object containing info about the event to create"
+// @Success 201 {object} object{eventId=string,shortId=string,editToken=string} "editToken is only returned to guests, who need to send it in the X-Edit-Token header to edit or delete the event"
 // @Router /events [post]
 func createEvent(c *gin.Context) {
 	payload := struct {
@@ -95,14 +127,39 @@ func createEvent(c *gin.Context) {
 		When2meetHref            *string  `json:"when2meetHref"`
 		CollectEmails            *bool    `json:"collectEmails"`
 
+		// Custom questions that respondents answer along with their response
+		Questions *[]models.Question `json:"questions"`
+
+		// IANA time zone that the dates were picked in
+		TimeZone *string `json:"timeZone"`
+
+		// Only for DOW events
+		Recurrence *models.Recurrence `json:"recurrence"`
+
 		// Only for availability groups
 		Attendees []string `json:"attendees"`
+
+		// Time after which responses are no longer accepted
+		ClosesAt *primitive.DateTime `json:"closesAt"`
 	}{}
 	if err := c.Bind(&payload); err != nil {
 		fmt.Println("Bind error:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+
+	if payload.Recurrence != nil && !isValidRecurrence(payload.Type, *payload.Recurrence) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidRecurrence})
+		return
+	}
+	if payload.TimeZone != nil && !utils.IsValidTimeZone(*payload.TimeZone) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidTimeZone})
+		return
+	}
+	if !prepareQuestions(payload.Questions) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidQuestions})
+		return
+	}
 	
 	// Log the MongoDB connection string (without credentials)
 	mongoURI := os.Getenv("MONGODB_URI")
@@ -133,10 +190,21 @@ func createEvent(c *gin.Context) {
 		fmt.Println("Creating event as guest user")
 	}
 
+	// Give guests a secret token to manage the event with, since anyone can open the event link
+	var editToken *string
+	if !signedIn {
+		token, err := utils.GenerateToken(32)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		editToken = &token
+	}
+
 	// Construct event object
 	event := models.Event{
 		Id:                       primitive.NewObjectID(),
 		OwnerId:                  ownerId,
+		EditToken:                editToken,
 		Name:                     payload.Name,
 		Duration:                 payload.Duration,
 		Dates:                    payload.Dates,
@@ -149,6 +217,10 @@ func createEvent(c *gin.Context) {
 		SendEmailAfterXResponses: payload.SendEmailAfterXResponses,
 		When2meetHref:            payload.When2meetHref,
 		CollectEmails:            payload.CollectEmails,
+		Questions:                payload.Questions,
+		TimeZone:                 payload.TimeZone,
+		Recurrence:               payload.Recurrence,
+		ClosesAt:                 payload.ClosesAt,
 		Type:                     payload.Type,
 		ResponsesList:            make([]models.EventResponse, 0),
 		SignUpResponses:          make(map[string]*models.SignUpResponse),
@@ -228,6 +300,11 @@ func createEvent(c *gin.Context) {
 	insertedId := result.InsertedID.(primitive.ObjectID).Hex()
 	fmt.Println("Successfully inserted event with ID:", insertedId)
 
+	// Close the event once the deadline passes
+	if event.ClosesAt != nil {
+		gcloud.CreateCloseEventTask(insertedId, *event.ClosesAt)
+	}
+
 	// Send slackbot message
 	var creator string
 	if signedIn {
@@ -237,14 +314,19 @@ func createEvent(c *gin.Context) {
 	}
 	slackbot.SendEventCreatedMessage(insertedId, creator, event)
 
-	c.JSON(http.StatusCreated, gin.H{"eventId": insertedId, "shortId": event.ShortId})
+	response := gin.H{"eventId": insertedId, "shortId": event.ShortId}
+	if editToken != nil {
+		response["editToken"] = *editToken
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // @Summary Edits an event based on its id
+// @Description Events created by guests can only be edited with the edit token that was returned when creating them
 // @Tags events
 // @Produce json
 // @Param eventId path string true "Event ID"
-// @Param payload body object{name=string,description=string,duration=float32,dates=[]string,type=models.EventType,signUpBlocks=[]models.SignUpBlock,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,attendees=[]string} true "Object containing info about the event to update"
+// @Param payload body object{name=string,description=string,duration=float32,dates=[]string,type=models.EventType,signUpBlocks=[]models.SignUpBlock,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,timeZone=string,recurrence=models.Recurrence,attendees=[]string,closesAt=string,questions=[]models.Question,version=int} true "Object containing info about the event to update"
 // @Success 200
 // @Router /events/{eventId} [put]
 func editEvent(c *gin.Context) {
@@ -270,13 +352,42 @@ func editEvent(c *gin.Context) {
 		SendEmailAfterXResponses *int     `json:"sendEmailAfterXResponses"`
 		CollectEmails            *bool    `json:"collectEmails"`
 
+		// Custom questions that respondents answer along with their response. Left unchanged when omitted, and
+		// removed with an empty list
+		Questions *[]models.Question `json:"questions"`
+
+		// IANA time zone that the dates were picked in
+		TimeZone *string `json:"timeZone"`
+
+		// Only for DOW events
+		Recurrence *models.Recurrence `json:"recurrence"`
+
 		// Only for availability groups
 		Attendees []string `json:"attendees"`
+
+		// Time after which responses are no longer accepted
+		ClosesAt *primitive.DateTime `json:"closesAt"`
+
+		// Version of the event that was edited, used to reject edits to an outdated event
+		Version *int `json:"version"`
 	}{}
 	if err := c.Bind(&payload); err != nil {
 		return
 	}
 
+	if payload.Recurrence != nil && !isValidRecurrence(payload.Type, *payload.Recurrence) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidRecurrence})
+		return
+	}
+	if payload.TimeZone != nil && !utils.IsValidTimeZone(*payload.TimeZone) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidTimeZone})
+		return
+	}
+	if !prepareQuestions(payload.Questions) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidQuestions})
+		return
+	}
+
 	eventId := c.Param("eventId")
 	event := db.GetEventByEitherId(eventId)
 	if event == nil {
@@ -284,38 +395,50 @@ func editEvent(c *gin.Context) {
 		return
 	}
 
-	// If user logged in, set owner id to their user id, otherwise set owner id to nil
-	session := sessions.Default(c)
-	userIdInterface := session.Get("userId")
-	userId, signedIn := userIdInterface.(string)
-	var ownerId primitive.ObjectID
-	if signedIn {
-		ownerId = utils.StringToObjectID(userId)
-	} else {
-		ownerId = primitive.NilObjectID
-	}
-
-	// If event has an owner id, check if user has permissions to edit event
-	if event.OwnerId != primitive.NilObjectID {
-		if event.OwnerId != ownerId {
-			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOwner})
+	// If event has an owner id or an edit token, check if user has permissions to edit event.
+	// Guest events created before edit tokens can still be edited by anyone
+	if event.OwnerId != primitive.NilObjectID || event.EditToken != nil {
+		if !authorizeEventRole(c, event, models.EditorRole) {
 			return
 		}
 	}
 
+	// Reject edits made to an outdated version of the event
+	if payload.Version != nil && *payload.Version != event.Version {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	}
+
 	// Update event
-	event.Name = payload.Name
-	event.Description = payload.Description
-	event.Duration = payload.Duration
-	event.Dates = payload.Dates
-	event.SignUpBlocks = payload.SignUpBlocks
-	event.StartOnMonday = payload.StartOnMonday
-	event.NotificationsEnabled = payload.NotificationsEnabled
-	event.BlindAvailabilityEnabled = payload.BlindAvailabilityEnabled
-	event.DaysOnly = payload.DaysOnly
-	event.SendEmailAfterXResponses = payload.SendEmailAfterXResponses
-	event.CollectEmails = payload.CollectEmails
-	event.Type = payload.Type
+	original := *event
+	closesAtChanged := utils.Coalesce(event.ClosesAt) != utils.Coalesce(payload.ClosesAt)
+	applyEdits := func(event *models.Event) {
+		event.Name = payload.Name
+		event.Description = payload.Description
+		event.Duration = payload.Duration
+		event.Dates = payload.Dates
+		event.SignUpBlocks = payload.SignUpBlocks
+		event.StartOnMonday = payload.StartOnMonday
+		event.NotificationsEnabled = payload.NotificationsEnabled
+		event.BlindAvailabilityEnabled = payload.BlindAvailabilityEnabled
+		event.DaysOnly = payload.DaysOnly
+		event.SendEmailAfterXResponses = payload.SendEmailAfterXResponses
+		event.CollectEmails = payload.CollectEmails
+		if payload.Questions != nil {
+			event.Questions = payload.Questions
+		}
+		event.Recurrence = payload.Recurrence
+		event.Type = payload.Type
+		if payload.TimeZone != nil {
+			event.TimeZone = payload.TimeZone
+		}
+		if closesAtChanged {
+			// Reopen the event until the new deadline
+			event.ClosesAt = payload.ClosesAt
+			event.Closed = false
+		}
+	}
+	applyEdits(event)
 
 	// Update remindees
 	if event.Type == models.DOW || event.Type == models.SPECIFIC_DATES {
@@ -337,8 +460,11 @@ func editEvent(c *gin.Context) {
 		}
 
 		for _, addedEmail := range added {
-			// Schedule email tasks
-			taskIds := gcloud.CreateEmailTask(addedEmail.Value, ownerName, event.Name, event.GetId())
+			// Schedule email tasks, unless the event doesn't accept responses anymore
+			var taskIds []string
+			if !event.IsClosed() {
+				taskIds = gcloud.CreateEmailTask(addedEmail.Value, ownerName, event.Name, event.GetId())
+			}
 			updatedRemindees = append(updatedRemindees, models.Remindee{
 				Email:     addedEmail.Value,
 				TaskIds:   taskIds,
@@ -357,6 +483,7 @@ func editEvent(c *gin.Context) {
 	}
 
 	// Update attendees
+	history := make([]models.EventHistoryEntry, 0)
 	if event.Type == models.GROUP {
 		origAttendees := utils.Coalesce(event.Attendees)
 		updatedAttendees := make([]models.Attendee, 0)
@@ -379,16 +506,17 @@ func editEvent(c *gin.Context) {
 
 		// Remove user from responses map
 		for _, removedEmail := range removed {
+			history = append(history, models.EventHistoryEntry{Action: models.AttendeeRemovedAction, Target: removedEmail.Value})
+
 			// Only delete response if it isn't the owner of the group
 			if removedEmail.Value != utils.Coalesce(owner).Email {
 				removedUser := db.GetUserByEmail(removedEmail.Value)
 				if removedUser != nil {
-					// Remove response from array
-					for i := range event.ResponsesList {
-						if event.ResponsesList[i].UserId == removedUser.Id.Hex() {
-							event.ResponsesList = append(event.ResponsesList[:i], event.ResponsesList[i+1:]...)
-							break
-						}
+					if err := db.DeleteEventResponse(event.Id, removedUser.Id.Hex()); err != nil {
+						logger.StdErr.Panicln(err)
+					}
+					if err := db.DeleteRespondentPollVotes(event.Id, removedUser.Id.Hex()); err != nil {
+						logger.StdErr.Panicln(err)
 					}
 				}
 			}
@@ -399,6 +527,8 @@ func editEvent(c *gin.Context) {
 		}
 
 		for _, addedEmail := range added {
+			history = append(history, models.EventHistoryEntry{Action: models.AttendeeAddedAction, Target: addedEmail.Value})
+
 			// Send invite email
 			availabilityGroupInviteEmailId := 9
 			listmonk.SendEmailAddSubscriberIfNotExist(addedEmail.Value, availabilityGroupInviteEmailId, bson.M{
@@ -430,21 +560,36 @@ func editEvent(c *gin.Context) {
 		event.Attendees = &updatedAttendees
 	}
 
-	// Update event object
-	_, err := db.EventsCollection.UpdateOne(
-		context.Background(),
-		bson.M{
-			"_id": event.Id,
-		},
-		bson.M{
-			"$set": event,
-		},
-	)
-
-	if err != nil {
+	// Update event object. If someone else updated the event in the meantime, the edits are applied again to the latest
+	// version of the event, keeping the remindees that responded and the attendees that declined since it was fetched
+	version, remindees, attendees := event.Version, event.Remindees, event.Attendees
+	_, err := saveEventUpdate(event, func(event *models.Event) bool {
+		if event.Version != version {
+			applyEdits(event)
+			event.Remindees = mergeRemindees(remindees, event.Remindees)
+			event.Attendees = mergeAttendees(attendees, event.Attendees)
+		}
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
 		logger.StdErr.Panicln(err)
 	}
 
+	// Close the event once the new deadline passes
+	if closesAtChanged && event.ClosesAt != nil {
+		gcloud.CreateCloseEventTask(event.Id.Hex(), *event.ClosesAt)
+	}
+
+	if fields := getEditedFields(&original, event); len(fields) > 0 {
+		history = append([]models.EventHistoryEntry{{Action: models.EventEditedAction, Fields: fields}}, history...)
+	}
+	recordEventHistory(c, event, "", history...)
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventEdited})
+
 	c.Status(http.StatusOK)
 }
 
@@ -518,6 +663,11 @@ func getEvent(c *gin.Context) {
 		event.SignUpResponses[userId] = response
 	}
 
+	// Populate collaborators
+	for i, collaborator := range utils.Coalesce(event.Collaborators) {
+		(*event.Collaborators)[i].User = db.GetUserById(collaborator.UserId.Hex())
+	}
+
 	// Create a copy of the event with responses in map format
 	c.JSON(http.StatusOK, event)
 }
@@ -553,21 +703,8 @@ func getResponses(c *gin.Context) {
 
 	// Filter availability slice based on timeMin and timeMax
 	for userId, response := range responsesMap {
-		subsetAvailability := make([]primitive.DateTime, 0)
-		for _, timestamp := range response.Availability {
-			if timestamp.Time().Compare(payload.TimeMin) >= 0 && timestamp.Time().Compare(payload.TimeMax) <= 0 {
-				subsetAvailability = append(subsetAvailability, timestamp)
-			}
-		}
-		response.Availability = subsetAvailability
-
-		subsetIfNeeded := make([]primitive.DateTime, 0)
-		for _, timestamp := range response.IfNeeded {
-			if timestamp.Time().Compare(payload.TimeMin) >= 0 && timestamp.Time().Compare(payload.TimeMax) <= 0 {
-				subsetIfNeeded = append(subsetIfNeeded, timestamp)
-			}
-		}
-		response.IfNeeded = subsetIfNeeded
+		response.Availability = response.Availability.Between(payload.TimeMin, payload.TimeMax)
+		response.IfNeeded = response.IfNeeded.Between(payload.TimeMin, payload.TimeMax)
 
 		subsetManualAvailability := make(map[primitive.DateTime][]primitive.DateTime)
 		for timestamp := range utils.Coalesce(response.ManualAvailability) {
@@ -582,12 +719,159 @@ func getResponses(c *gin.Context) {
 	c.JSON(http.StatusOK, responsesMap)
 }
 
+// How often a comment is sent on idle event streams so that proxies don't close the connection
+const streamHeartbeatInterval = 30 * time.Second
+
+// @Summary Streams live updates to an event as Server-Sent Events
+// @Description Each event is named after the type of the update and contains an eventstream.Message. Clients should refetch the event or its responses when they receive one
+// @Tags events
+// @Produce text/event-stream
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} eventstream.Message
+// @Router /events/{eventId}/stream [get]
+func streamEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	messages, unsubscribe := eventstream.Subscribe(event.Id.Hex())
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case message := <-messages:
+			c.SSEvent(string(message.Type), message)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		return true
+	})
+}
+
+// @Summary Gets the best times for an event, ranked by the number of respondents available
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param length query int false "Length of the meeting in minutes (defaults to 60, ignored for days only events)"
+// @Param limit query int false "Maximum number of slots to return (defaults to 10, 0 returns all slots)"
+// @Success 200 {object} []availability.TimeSlot
+// @Router /events/{eventId}/best-times [get]
+func getBestTimes(c *gin.Context) {
+	// Bind query parameters
+	payload := struct {
+		Length *int `form:"length"`
+		Limit  *int `form:"limit"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	// Fetch event
+	eventId := c.Param("eventId")
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	// Only the owners and viewers can see who is available when blind availability is enabled
+	if utils.Coalesce(event.BlindAvailabilityEnabled) && !authorizeEventRole(c, event, models.ViewerRole) {
+		return
+	}
+
+	length := 60
+	if payload.Length != nil {
+		length = *payload.Length
+	}
+	limit := 10
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+
+	bestTimes := availability.GetBestTimes(event, availability.BestTimesOptions{
+		Length: time.Duration(length) * time.Minute,
+		Limit:  limit,
+	})
+
+	c.JSON(http.StatusOK, bestTimes)
+}
+
+// @Summary Gets the candidate weekly slots of a recurring meeting poll, ranked by how many respondents can attend every week
+// @Description Each slot on the DOW grid is checked against the connected calendars of every signed in respondent for each week of the recurrence, and weeks with conflicts are flagged.
+// @Description Only the event's owners and editors can see this, since it is based on the respondents' calendars
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param length query int false "Length of the meeting in minutes, defaults to 60"
+// @Param limit query int false "Maximum number of slots to return, defaults to 10"
+// @Success 200 {object} []availability.RecurringSlot
+// @Router /events/{eventId}/recurring-slots [get]
+func getRecurringSlots(c *gin.Context) {
+	// Bind query parameters
+	payload := struct {
+		Length *int `form:"length"`
+		Limit  *int `form:"limit"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	// Fetch event
+	eventId := c.Param("eventId")
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	if !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	if event.Type != models.DOW || event.Recurrence == nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EventNotRecurring})
+		return
+	}
+
+	length := 60
+	if payload.Length != nil {
+		length = *payload.Length
+	}
+	limit := 10
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+
+	busyTimes := getRespondentsCalendarEvents(event, event.Recurrence.StartDate.Time(), event.Recurrence.EndDate.Time())
+	recurringSlots := availability.GetRecurringSlots(event, busyTimes, availability.BestTimesOptions{
+		Length: time.Duration(length) * time.Minute,
+		Limit:  limit,
+	})
+
+	c.JSON(http.StatusOK, recurringSlots)
+}
+
 // @Summary Updates the current user's availability
 // @Tags events
 // @Accept json
 // @Produce json
 // @Param eventId path string true "Event ID"
-// @Param payload body object{availability=[]string,ifNeeded=[]string,guest=bool,name=string,useCalendarAvailability=bool,enabledCalendars=map[string][]string,manualAvailability=map[string][]string,calendarOptions=models.CalendarOptions,signUpBlockIds=[]string} true "Object containing info about the event response to update"
+// @Param payload body object{availability=[]string,ifNeeded=[]string,guest=bool,name=string,useCalendarAvailability=bool,enabledCalendars=map[string][]string,manualAvailability=map[string][]string,calendarOptions=models.CalendarOptions,timeZone=string,signUpBlockIds=[]string,answers=map[string][]string} true "Object containing info about the event response to update"
 // @Success 200
 // @Router /events/{eventId}/response [post]
 func updateEventResponse(c *gin.Context) {
@@ -606,12 +890,22 @@ func updateEventResponse(c *gin.Context) {
 		ManualAvailability      *map[primitive.DateTime][]primitive.DateTime `json:"manualAvailability"`
 		CalendarOptions         *models.CalendarOptions                      `json:"calendarOptions"`
 
+		// IANA time zone that the availability was filled out in
+		TimeZone *string `json:"timeZone"`
+
 		// Sign up form variables
 		SignUpBlockIds []primitive.ObjectID `json:"signUpBlockIds"`
+
+		// Answers to the custom questions of the event
+		Answers models.Answers `json:"answers"`
 	}{}
 	if err := c.Bind(&payload); err != nil {
 		return
 	}
+	if payload.TimeZone != nil && !utils.IsValidTimeZone(*payload.TimeZone) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidTimeZone})
+		return
+	}
 	session := sessions.Default(c)
 	eventId := c.Param("eventId")
 	event := db.GetEventByEitherId(eventId)
@@ -619,9 +913,28 @@ func updateEventResponse(c *gin.Context) {
 		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
 		return
 	}
+	if event.IsClosed() {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.EventClosed})
+		return
+	}
+
+	// Don't allow changes to locked responses
+	respondentId := payload.Name
+	if !*payload.Guest {
+		respondentId, _ = session.Get("userId").(string)
+	}
+	if event.IsResponseLocked(respondentId) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.ResponsesLocked})
+		return
+	}
+	if !event.AreValidAnswers(payload.Answers) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidAnswers})
+		return
+	}
 
 	var userIdString string
 	var userHasResponded bool
+	var attendeeEmail string
 	if !utils.Coalesce(event.IsSignUpForm) {
 		// Populate response differently if guest vs signed in user
 		var response models.Response
@@ -631,8 +944,10 @@ func updateEventResponse(c *gin.Context) {
 			response = models.Response{
 				Name:         payload.Name,
 				Email:        payload.Email,
-				Availability: payload.Availability,
-				IfNeeded:     payload.IfNeeded,
+				Availability: models.NewSlotSet(payload.Availability),
+				IfNeeded:     models.NewSlotSet(payload.IfNeeded),
+				TimeZone:     payload.TimeZone,
+				Answers:      payload.Answers,
 			}
 		} else {
 			userIdInterface := session.Get("userId")
@@ -646,24 +961,19 @@ func updateEventResponse(c *gin.Context) {
 
 			response = models.Response{
 				UserId:                  userId,
-				Availability:            payload.Availability,
-				IfNeeded:                payload.IfNeeded,
+				Availability:            models.NewSlotSet(payload.Availability),
+				IfNeeded:                models.NewSlotSet(payload.IfNeeded),
 				UseCalendarAvailability: payload.UseCalendarAvailability,
 				EnabledCalendars:        payload.EnabledCalendars,
 				CalendarOptions:         payload.CalendarOptions,
+				TimeZone:                payload.TimeZone,
+				Answers:                 payload.Answers,
 			}
 
 			if event.Type == models.GROUP {
-				user := db.GetUserById(userIdString)
-
-				// Set declined to false (in case user declined group in the past)
-				if user != nil {
-					for i, attendee := range utils.Coalesce(event.Attendees) {
-						if strings.EqualFold(attendee.Email, user.Email) {
-							(*event.Attendees)[i].Declined = utils.FalsePtr()
-							break
-						}
-					}
+				// Set declined to false when updating the event (in case user declined group in the past)
+				if user := db.GetUserById(userIdString); user != nil {
+					attendeeEmail = user.Email
 				}
 
 				// Update manual availability
@@ -703,22 +1013,19 @@ func updateEventResponse(c *gin.Context) {
 			}
 		}
 
-		// Check if user has responded to event before (edit response) or not (new response)
-		idx, _ := findResponse(event.ResponsesList, userIdString)
-		userHasResponded = idx != -1
+		// Update the user's response, and check if they have responded to the event before (edit response) or not (new response)
+		isNewResponse, err := db.UpsertEventResponse(event.Id, userIdString, &response)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		userHasResponded = !isNewResponse
 
-		// Update event responses
-		if userHasResponded {
-			event.ResponsesList[idx].Response = &response
-		} else {
-			event.ResponsesList = append(event.ResponsesList, models.EventResponse{
-				UserId:   userIdString,
-				Response: &response,
-			})
+		// Reload the responses so that the number of responses includes everyone responding at the same time
+		if isNewResponse && utils.Coalesce(event.SendEmailAfterXResponses) > 0 {
+			event.ResponsesList = db.GetEventResponses(event.Id)
 		}
 	} else {
 		var response models.SignUpResponse
-		var userIdString string
 		// Populate response differently if guest vs signed in user
 		if *payload.Guest {
 			userIdString = payload.Name
@@ -727,6 +1034,7 @@ func updateEventResponse(c *gin.Context) {
 				SignUpBlockIds: payload.SignUpBlockIds,
 				Name:           payload.Name,
 				Email:          payload.Email,
+				Answers:        payload.Answers,
 			}
 		} else {
 			userIdInterface := session.Get("userId")
@@ -740,19 +1048,59 @@ func updateEventResponse(c *gin.Context) {
 			response = models.SignUpResponse{
 				SignUpBlockIds: payload.SignUpBlockIds,
 				UserId:         utils.StringToObjectID(userIdString),
+				Answers:        payload.Answers,
 			}
 		}
 
-		// Check if user has responded to event before (edit response) or not (new response)
-		_, userHasResponded = event.SignUpResponses[userIdString]
+		// Update the user's sign up response, and check if they have responded to the event before (edit response) or not (new response)
+		isNewResponse, err := db.SetSignUpResponse(event.Id, userIdString, &response)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		userHasResponded = !isNewResponse
+	}
+
+	// Update the rest of the event, retrying on the latest version of the event if someone else updates it at the same time
+	sendEmailAfterXResponses := false
+	_, err := saveEventUpdate(event, func(event *models.Event) bool {
+		changed := false
+
+		if len(attendeeEmail) > 0 {
+			for i, attendee := range utils.Coalesce(event.Attendees) {
+				if strings.EqualFold(attendee.Email, attendeeEmail) {
+					if utils.Coalesce(attendee.Declined) {
+						(*event.Attendees)[i].Declined = utils.FalsePtr()
+						changed = true
+					}
+					break
+				}
+			}
+		}
 
-		// Update event responses
-		if event.SignUpResponses == nil {
-			event.SignUpResponses = make(map[string]*models.SignUpResponse)
+		// Set SendEmailAfterXResponses variable to -1 to prevent additional emails from being sent
+		numResponses := utils.Coalesce(event.SendEmailAfterXResponses)
+		sendEmailAfterXResponses = numResponses > 0 && !userHasResponded && numResponses == len(event.ResponsesList)
+		if sendEmailAfterXResponses {
+			*event.SendEmailAfterXResponses = -1
+			changed = true
 		}
-		event.SignUpResponses[userIdString] = &response
+
+		return changed
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
 	}
 
+	messageType, historyAction := eventstream.ResponseAdded, models.ResponseAddedAction
+	if userHasResponded {
+		messageType, historyAction = eventstream.ResponseUpdated, models.ResponseUpdatedAction
+	}
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: messageType, UserId: userIdString})
+	recordEventHistory(c, event, payload.Name, models.EventHistoryEntry{Action: historyAction, Target: userIdString})
+
 	// Send notification emails
 	if (utils.Coalesce(event.NotificationsEnabled) || event.Type == models.GROUP) && !userHasResponded && userIdString != event.OwnerId.Hex() {
 		// Send email asynchronously
@@ -798,11 +1146,7 @@ func updateEventResponse(c *gin.Context) {
 	}
 
 	// Send email after X responses
-	sendEmailAfterXResponses := utils.Coalesce(event.SendEmailAfterXResponses)
-	if sendEmailAfterXResponses > 0 && !userHasResponded && sendEmailAfterXResponses == len(event.ResponsesList) {
-		// Set SendEmailAfterXResponses variable to -1 to prevent additional emails from being sent
-		*event.SendEmailAfterXResponses = -1
-
+	if sendEmailAfterXResponses {
 		// Send email asynchronously
 		go func() {
 			// Recover from panics
@@ -827,16 +1171,6 @@ func updateEventResponse(c *gin.Context) {
 		}()
 	}
 
-	// Update event in mongodb
-	_, err := db.EventsCollection.UpdateByID(
-		context.Background(),
-		event.Id,
-		bson.M{"$set": event},
-	)
-	if err != nil {
-		logger.StdErr.Panicln(err)
-	}
-
 	c.JSON(http.StatusOK, gin.H{})
 }
 
@@ -865,14 +1199,40 @@ func deleteEventResponse(c *gin.Context) {
 		return
 	}
 
+	// The key of the deleted response
+	respondentId := payload.UserId
+	if *payload.Guest {
+		respondentId = payload.Name
+	}
+
+	// Only the owners and editors can remove responses after the event closes or while they are locked
+	if !event.HasRole(getSessionUserId(c), models.EditorRole) {
+		if event.IsClosed() {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.EventClosed})
+			return
+		}
+		if event.IsResponseLocked(respondentId) {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.ResponsesLocked})
+			return
+		}
+	}
+
+	// The attendee that is leaving the group
+	var attendeeEmail string
+
 	if *payload.Guest {
 		if utils.Coalesce(event.IsSignUpForm) {
-			delete(event.SignUpResponses, payload.Name)
+			if err := db.DeleteSignUpResponse(event.Id, payload.Name); err != nil {
+				logger.StdErr.Panicln(err)
+			}
 		} else {
-			// Remove response from array
-			for i := range event.ResponsesList {
-				if event.ResponsesList[i].Response.Name == payload.Name {
-					event.ResponsesList = append(event.ResponsesList[:i], event.ResponsesList[i+1:]...)
+			// Remove the guest's response
+			for _, eventResponse := range event.ResponsesList {
+				if eventResponse.Response != nil && eventResponse.Response.Name == payload.Name {
+					if err := db.DeleteEventResponse(event.Id, eventResponse.UserId); err != nil {
+						logger.StdErr.Panicln(err)
+					}
+					respondentId = eventResponse.UserId
 					break
 				}
 			}
@@ -886,51 +1246,65 @@ func deleteEventResponse(c *gin.Context) {
 		}
 		userIdString := userIdInterface.(string)
 
-		// Don't allow user to delete availability of other users if they aren't the owner of the event
-		if payload.UserId != userIdString && event.OwnerId.Hex() != userIdString {
-			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOwner})
+		// Don't allow user to delete availability of other users if they can't edit the event
+		if payload.UserId != userIdString && !authorizeEventRole(c, event, models.EditorRole) {
 			c.Abort()
 			return
 		}
 
 		if utils.Coalesce(event.IsSignUpForm) {
-			delete(event.SignUpResponses, payload.UserId)
+			if err := db.DeleteSignUpResponse(event.Id, payload.UserId); err != nil {
+				logger.StdErr.Panicln(err)
+			}
 		} else {
-			// Remove response from array
-			for i := range event.ResponsesList {
-				if event.ResponsesList[i].UserId == payload.UserId {
-					event.ResponsesList = append(event.ResponsesList[:i], event.ResponsesList[i+1:]...)
-					break
-				}
+			if err := db.DeleteEventResponse(event.Id, payload.UserId); err != nil {
+				logger.StdErr.Panicln(err)
 			}
 		}
 
-		// If this event is a Group, also make the attendee "leave the group" by setting "declined" to true
+		// If this event is a Group, also make the attendee whose response was deleted "leave the group" by setting "declined" to true
 		if event.Type == models.GROUP {
-			user := db.GetUserById(userIdString)
-			if user != nil {
-				for i, attendee := range utils.Coalesce(event.Attendees) {
-					if strings.EqualFold(attendee.Email, user.Email) {
-						(*event.Attendees)[i].Declined = utils.TruePtr()
-						break
-					}
+			if user := db.GetUserById(payload.UserId); user != nil {
+				attendeeEmail = user.Email
+			}
+		}
+	}
+
+	// Update attendees in mongodb, retrying on the latest version of the event if someone else updates it at the same time
+	declined, err := saveEventUpdate(event, func(event *models.Event) bool {
+		for i, attendee := range utils.Coalesce(event.Attendees) {
+			if len(attendeeEmail) > 0 && strings.EqualFold(attendee.Email, attendeeEmail) {
+				if utils.Coalesce(attendee.Declined) {
+					return false
 				}
+				(*event.Attendees)[i].Declined = utils.TruePtr()
+				return true
 			}
 		}
+		return false
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
 	}
 
-	// Update responses in mongodb
-	_, err := db.EventsCollection.UpdateByID(
-		context.Background(),
-		event.Id,
-		bson.M{
-			"$set": event,
-		},
-	)
-	if err != nil {
+	// Votes are part of the response, so they are deleted along with it
+	if err := db.DeleteRespondentPollVotes(event.Id, respondentId); err != nil {
 		logger.StdErr.Panicln(err)
 	}
 
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.ResponseDeleted, UserId: respondentId})
+	if len(utils.Coalesce(event.Polls)) > 0 {
+		eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.PollsUpdated})
+	}
+	history := []models.EventHistoryEntry{{Action: models.ResponseDeletedAction, Target: respondentId}}
+	if declined {
+		history = append(history, models.EventHistoryEntry{Action: models.AttendeeRemovedAction, Target: attendeeEmail})
+	}
+	recordEventHistory(c, event, payload.Name, history...)
+
 	c.JSON(http.StatusOK, gin.H{})
 }
 
@@ -958,35 +1332,43 @@ func userResponded(c *gin.Context) {
 		return
 	}
 
-	// Update responded boolean for the given email
-	if event.Remindees == nil {
-		c.JSON(http.StatusNotFound, responses.Error{Error: errs.RemindeeEmailNotFound})
+	// Update responded boolean for the given email, retrying on the latest version of the event if someone else updates it at the same time
+	var remindee models.Remindee
+	remindeeFound := false
+	saved, err := saveEventUpdate(event, func(event *models.Event) bool {
+		index := utils.Find(utils.Coalesce(event.Remindees), func(r models.Remindee) bool {
+			return r.Email == payload.Email
+		})
+		remindeeFound = index != -1
+		if !remindeeFound || utils.Coalesce((*event.Remindees)[index].Responded) {
+			// If remindee has already responded, don't update db
+			return false
+		}
+
+		(*event.Remindees)[index].Responded = utils.TruePtr()
+		remindee = (*event.Remindees)[index]
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
 		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
 	}
-	index := utils.Find(*event.Remindees, func(r models.Remindee) bool {
-		return r.Email == payload.Email
-	})
-	if index == -1 {
+	if !remindeeFound {
 		c.JSON(http.StatusNotFound, responses.Error{Error: errs.RemindeeEmailNotFound})
 		return
 	}
-	if *(*event.Remindees)[index].Responded {
-		// If remindee has already responded, just return and don't update db
+	if !saved {
 		c.JSON(http.StatusOK, gin.H{})
 		return
 	}
-	(*event.Remindees)[index].Responded = utils.TruePtr()
 
 	// Delete the reminder email tasks
-	for _, taskId := range (*event.Remindees)[index].TaskIds {
+	for _, taskId := range remindee.TaskIds {
 		gcloud.DeleteEmailTask(taskId)
 	}
 
-	// Update event in database
-	db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
-		"$set": event,
-	})
-
 	// Email owner of event if all remindees have responded
 	everyoneResponded := true
 	for _, remindee := range *event.Remindees {
@@ -998,6 +1380,10 @@ func userResponded(c *gin.Context) {
 	if everyoneResponded {
 		// Get owner
 		owner := db.GetUserById(event.OwnerId.Hex())
+		if owner == nil {
+			c.JSON(http.StatusOK, gin.H{})
+			return
+		}
 
 		// Get event url
 		var baseUrl string
@@ -1045,25 +1431,35 @@ func declineInvite(c *gin.Context) {
 	userInterface, _ := c.Get("authUser")
 	user := userInterface.(*models.User)
 
-	// Check if user is in attendees array
-	index := utils.Find(utils.Coalesce(event.Attendees), func(a models.Attendee) bool {
-		return strings.EqualFold(a.Email, user.Email)
+	// Decline invite, retrying on the latest version of the event if someone else updates it at the same time
+	attendeeFound := false
+	declined, err := saveEventUpdate(event, func(event *models.Event) bool {
+		// Check if user is in attendees array
+		index := utils.Find(utils.Coalesce(event.Attendees), func(a models.Attendee) bool {
+			return strings.EqualFold(a.Email, user.Email)
+		})
+		attendeeFound = index != -1
+		if !attendeeFound || utils.Coalesce((*event.Attendees)[index].Declined) {
+			return false
+		}
+
+		(*event.Attendees)[index].Declined = utils.TruePtr()
+		return true
 	})
-	if index == -1 {
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if !attendeeFound {
 		// User not in attendees array
 		c.JSON(http.StatusNotFound, responses.Error{Error: errs.AttendeeEmailNotFound})
 		return
 	}
 
-	// Decline invite
-	(*event.Attendees)[index].Declined = utils.TruePtr()
-
-	// Update event in database
-	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
-		"$set": event,
-	})
-	if err != nil {
-		logger.StdErr.Panicln(err)
+	if declined {
+		recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.AttendeeRemovedAction, Target: user.Email})
 	}
 
 	c.JSON(http.StatusOK, gin.H{})
@@ -1190,7 +1586,9 @@ func getCalendarAvailabilities(c *gin.Context) {
 	c.JSON(http.StatusOK, userIdToCalendarEvents)
 }
 
-// @Summary Deletes an event based on its id
+// @Summary Moves an event to the trash based on its id
+// @Description The event can be restored until it is purged after the retention period. Pending reminder emails are cancelled.
+// @Description Events created by guests can only be deleted with the edit token that was returned when creating them
 // @Tags events
 // @Produce json
 // @Param eventId path string true "Event ID"
@@ -1206,18 +1604,183 @@ func deleteEvent(c *gin.Context) {
 		return
 	}
 
-	userInterface, _ := c.Get("authUser")
-	user := userInterface.(*models.User)
+	event := db.GetEventById(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
 
-	_, err = db.EventsCollection.DeleteOne(context.Background(), bson.M{
-		"_id":     objectId,
-		"ownerId": user.Id,
-	})
+	// Make sure user has permission to delete this event
+	if !authorizeEventRole(c, event, models.OwnerRole) {
+		return
+	}
+
+	deleted, err := db.SoftDeleteEvent(objectId)
 	if err != nil {
 		logger.StdErr.Panicln(err)
 	}
 
-	c.Status(http.StatusOK)
+	if deleted {
+		cancelReminders(event)
+		eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventDeleted})
+		recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.EventDeletedAction})
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Restores an event from the trash
+// @Description Only possible until the event is purged after the retention period. Reminder emails that were cancelled when deleting the event are not sent again
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/restore [post]
+func restoreEvent(c *gin.Context) {
+	event := db.GetDeletedEventById(c.Param("eventId"))
+	if event == nil || time.Since(event.DeletedAt.Time()) > models.EventRetentionPeriod {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	// Make sure user has permission to restore this event
+	if !authorizeEventRole(c, event, models.OwnerRole) {
+		return
+	}
+
+	if err := db.RestoreEvent(event.Id); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.EventRestoredAction})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Makes the signed in user the owner of an event created by a guest
+// @Description Requires the edit token that was returned when creating the event in the X-Edit-Token header. The edit token stops working once the event is claimed
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/claim [post]
+func claimEvent(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if event.OwnerId != primitive.NilObjectID {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EventAlreadyOwned})
+		return
+	}
+	if !hasEditToken(c, event) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.InvalidEditToken})
+		return
+	}
+
+	// Only claim the event if nobody else claimed it in the meantime
+	user := utils.GetAuthUser(c)
+	result, err := db.EventsCollection.UpdateOne(context.Background(), bson.M{
+		"_id":       event.Id,
+		"ownerId":   nil,
+		"editToken": *event.EditToken,
+	}, bson.M{
+		"$set":   bson.M{"ownerId": user.Id},
+		"$unset": bson.M{"editToken": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EventAlreadyOwned})
+		return
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventEdited})
+	recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.OwnershipTransferredAction, Target: user.Email})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Transfers ownership of an event or availability group to another user
+// @Description Only owners can transfer an event. The new owner is removed from the collaborators, and for groups is added to the attendees.
+// @Description The previous owner loses access unless they are a collaborator, but keeps their response. The edit token of a guest event stops working once it is transferred
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{email=string} true "Object containing the email of the new owner"
+// @Success 200
+// @Router /events/{eventId}/transfer [post]
+func transferEvent(c *gin.Context) {
+	payload := struct {
+		Email string `json:"email" binding:"required"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.OwnerRole) {
+		return
+	}
+
+	newOwner := db.GetUserByEmail(payload.Email)
+	if newOwner == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+
+	transferred, err := saveEventUpdate(event, func(event *models.Event) bool {
+		if event.OwnerId == newOwner.Id {
+			return false
+		}
+
+		// Keep track of whose calendar the scheduled event is in, since it defaults to the owner's
+		if len(event.CalendarEventId) > 0 && event.CalendarUserId == primitive.NilObjectID {
+			event.CalendarUserId = event.OwnerId
+		}
+		event.OwnerId = newOwner.Id
+		event.EditToken = nil
+
+		// The owner can't also be a collaborator
+		collaborators := utils.Coalesce(event.Collaborators)
+		if i := utils.Find(collaborators, func(collaborator models.Collaborator) bool { return collaborator.UserId == newOwner.Id }); i != -1 {
+			collaborators = append(collaborators[:i], collaborators[i+1:]...)
+			event.Collaborators = &collaborators
+		}
+
+		// The owner of a group is always one of its attendees
+		if event.Type == models.GROUP {
+			attendees := utils.Coalesce(event.Attendees)
+			if i := utils.Find(attendees, func(a models.Attendee) bool { return strings.EqualFold(a.Email, newOwner.Email) }); i != -1 {
+				attendees[i].Declined = utils.FalsePtr()
+			} else {
+				attendees = append(attendees, models.Attendee{Email: newOwner.Email, Declined: utils.FalsePtr()})
+			}
+			event.Attendees = &attendees
+		}
+
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if transferred {
+		eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventEdited})
+		recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.OwnershipTransferredAction, Target: newOwner.Email})
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
 }
 
 // @Summary Duplicate event
@@ -1237,8 +1800,6 @@ func duplicateEvent(c *gin.Context) {
 	}
 
 	eventId := c.Param("eventId")
-	userInterface, _ := c.Get("authUser")
-	user := userInterface.(*models.User)
 
 	// Get event
 	event := db.GetEventByEitherId(eventId)
@@ -1247,8 +1808,8 @@ func duplicateEvent(c *gin.Context) {
 		return
 	}
 
-	// Make sure user has permission to duplicate this event
-	if event.OwnerId != user.Id {
+	// Make sure user has permission to duplicate this event. The duplicate keeps the owner and collaborators of the event
+	if !event.HasRole(utils.GetAuthUser(c).Id, models.EditorRole) {
 		c.Status(http.StatusForbidden)
 		return
 	}
@@ -1256,8 +1817,26 @@ func duplicateEvent(c *gin.Context) {
 	// Update event
 	event.Id = primitive.NewObjectID()
 	event.Name = payload.EventName
-	if !*payload.CopyAvailability {
-		event.ResponsesList = make([]models.EventResponse, 0)
+
+	// The duplicate accepts responses until it is given its own deadline
+	event.ClosesAt = nil
+	event.Closed = false
+
+	// The duplicate shares the meeting link, but removing it from the duplicate shouldn't cancel the meeting
+	event.MeetCalendarEvent = nil
+
+	// The scheduled event was written to the calendar of the original event, so the duplicate starts unscheduled
+	event.ScheduledEvent = nil
+	event.CalendarEventId = ""
+	event.CalendarAccountKey = ""
+	event.CalendarId = ""
+	event.CalendarUserId = primitive.NilObjectID
+	event.ExpectedRespondents = nil
+	event.ScheduleSequence = 0
+
+	// The reminder tasks belong to the original event, so deleting the duplicate mustn't cancel them
+	for i := range utils.Coalesce(event.Remindees) {
+		(*event.Remindees)[i].TaskIds = nil
 	}
 
 	// Generate short id
@@ -1270,12 +1849,137 @@ func duplicateEvent(c *gin.Context) {
 		logger.StdErr.Panicln(err)
 	}
 
+	// Copy availability
+	if *payload.CopyAvailability {
+		if err := db.InsertEventResponses(event.Id, event.ResponsesList); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+
 	insertedId := result.InsertedID.(primitive.ObjectID).Hex()
 	c.JSON(http.StatusCreated, gin.H{"eventId": insertedId, "shortId": shortId})
 }
 
+// @Summary Adds a collaborator to an event, or changes their role if they are already a collaborator
+// @Description Only owners can manage collaborators
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{email=string,role=models.CollaboratorRole} true "Object containing the email of the user to add and their role"
+// @Success 200
+// @Router /events/{eventId}/collaborators [post]
+func setCollaborator(c *gin.Context) {
+	payload := struct {
+		Email string                  `json:"email" binding:"required"`
+		Role  models.CollaboratorRole `json:"role" binding:"required"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+	if !payload.Role.IsValid() {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidRole})
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.OwnerRole) {
+		return
+	}
+
+	collaboratorUser := db.GetUserByEmail(payload.Email)
+	if collaboratorUser == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+	if collaboratorUser.Id == event.OwnerId {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.CollaboratorIsOwner})
+		return
+	}
+
+	updated, err := saveEventUpdate(event, func(event *models.Event) bool {
+		collaborators := utils.Coalesce(event.Collaborators)
+		if i := utils.Find(collaborators, func(collaborator models.Collaborator) bool { return collaborator.UserId == collaboratorUser.Id }); i != -1 {
+			if collaborators[i].Role == payload.Role {
+				return false
+			}
+			collaborators[i].Role = payload.Role
+		} else {
+			collaborators = append(collaborators, models.Collaborator{UserId: collaboratorUser.Id, Role: payload.Role})
+		}
+		event.Collaborators = &collaborators
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if updated {
+		eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventEdited})
+		recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.CollaboratorUpdatedAction, Target: collaboratorUser.Id.Hex()})
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Removes a collaborator from an event
+// @Description Owners can remove anyone, and collaborators can remove themselves
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param userId path string true "User ID of the collaborator"
+// @Success 200
+// @Router /events/{eventId}/collaborators/{userId} [delete]
+func removeCollaborator(c *gin.Context) {
+	collaboratorId, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if collaboratorId != utils.GetAuthUser(c).Id && !authorizeEventRole(c, event, models.OwnerRole) {
+		return
+	}
+
+	removed, err := saveEventUpdate(event, func(event *models.Event) bool {
+		collaborators := utils.Coalesce(event.Collaborators)
+		i := utils.Find(collaborators, func(collaborator models.Collaborator) bool { return collaborator.UserId == collaboratorId })
+		if i == -1 {
+			return false
+		}
+		collaborators = append(collaborators[:i], collaborators[i+1:]...)
+		event.Collaborators = &collaborators
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if removed {
+		eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventEdited})
+		recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.CollaboratorRemovedAction, Target: collaboratorId.Hex()})
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
 // @Summary Create a Google Meet link for an event
-// @Description Creates a Google Meet link for the specified event at the given time and duration
+// @Description Creates a Google Meet link for the specified event at the given time and duration, and saves it to the event
 // @Tags events
 // @Accept json
 // @Produce json
@@ -1292,138 +1996,988 @@ func createGoogleMeet(c *gin.Context) {
 		return
 	}
 
-	// Get the authenticated user from context (set by AuthRequired middleware)
-	userInterface, exists := c.Get("authUser")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
+	createMeetLinkFromRequest(c, models.GoogleMeetProvider, req.EventId, req.StartDateTime, req.DurationMinutes, req.Title)
+}
+
+// CreateTeamsMeetingRequest struct for creating a Teams meeting
+type CreateTeamsMeetingRequest struct {
+	EventId         string `json:"eventId" binding:"required"`
+	StartDateTime   string `json:"startDateTime" binding:"required"`
+	DurationMinutes int    `json:"durationMinutes" binding:"required"`
+	Title           string `json:"title" binding:"required"`
+}
+
+// @Summary Create a Microsoft Teams meeting link for an event
+// @Description Creates an event with a Teams meeting in the owner's Outlook calendar at the given time and duration, and saves the join url to the event
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param request body CreateTeamsMeetingRequest true "Create Teams meeting request"
+// @Success 200 {object} map[string]string
+// @Router /events/create-teams-meeting [post]
+func createTeamsMeeting(c *gin.Context) {
+	var req CreateTeamsMeetingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 		return
 	}
-	user := userInterface.(*models.User)
 
-	// Verify the event exists
-	event := db.GetEventById(req.EventId)
+	createMeetLinkFromRequest(c, models.TeamsProvider, req.EventId, req.StartDateTime, req.DurationMinutes, req.Title)
+}
+
+// Implements the shared functionality of the create-google-meet and create-teams-meeting routes
+func createMeetLinkFromRequest(c *gin.Context, provider models.ConferencingProvider, eventId string, startDateTime string, durationMinutes int, title string) {
+	event := db.GetEventById(eventId)
 	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
 		return
 	}
 
-	// Verify user owns the event
-	if event.OwnerId != user.Id {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only the event owner can create a Google Meet link for this event"})
+	startTime, err := time.Parse(time.RFC3339, startDateTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date/time format: " + err.Error()})
 		return
 	}
 
-	// --- Find the user's Google Calendar Account ---
-	var googleAccount *models.CalendarAccount
-	var googleAccountAuth *models.OAuth2CalendarAuth
-	for _, account := range user.CalendarAccounts {
-		if account.CalendarType == models.GoogleCalendarType && account.OAuth2CalendarAuth != nil {
-			googleAccount = &account // Keep a pointer to the account
-			googleAccountAuth = account.OAuth2CalendarAuth
-			break
-		}
-	}
+	createMeetLink(c, event, provider, conferencing.MeetingOptions{
+		Title:     title,
+		StartTime: startTime,
+		EndTime:   startTime.Add(time.Duration(durationMinutes) * time.Minute),
+	})
+}
+
+// @Summary Adds a video conferencing link to an event
+// @Description Creates a meeting with the given provider and saves its link to the event. Google Meet and Teams meetings are created in the owner's connected calendar and default to the scheduled time of the event.
+// @Description Jitsi links need no account and can be pointed at a self-hosted server with JITSI_URL_TEMPLATE, and custom links are saved as is
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{provider=models.ConferencingProvider,startDate=string,endDate=string,title=string,link=string} true "Object containing the provider, the time of the meeting for providers that need one, and the link for custom links"
+// @Success 200 {object} object{meetLink=string,meetProvider=models.ConferencingProvider,startTime=string,endTime=string}
+// @Router /events/{eventId}/conference [post]
+func createConference(c *gin.Context) {
+	payload := struct {
+		Provider models.ConferencingProvider `json:"provider" binding:"required"`
 
-	if googleAccountAuth == nil || googleAccountAuth.RefreshToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User does not have a linked Google Calendar account with necessary permissions"})
+		// Default to the scheduled time of the event
+		StartDate *primitive.DateTime `json:"startDate"`
+		EndDate   *primitive.DateTime `json:"endDate"`
+
+		// Defaults to the name of the event
+		Title *string `json:"title"`
+
+		// Only for custom links
+		Link *string `json:"link"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
 		return
 	}
 
-	// --- Refresh Token if Necessary (using existing auth service) ---
-	// The google_api.GetCalendarService helper already handles token refresh via the token source
-	// However, we might need to update the stored token if it was refreshed.
-	// Let's call RefreshUserTokenIfNecessary first to be safe and ensure DB is updated.
-	accountKey := utils.GetCalendarAccountKey(googleAccount.Email, models.GoogleCalendarType)
-	auth.RefreshUserTokenIfNecessary(user, models.Set[string]{accountKey: {}})
-	// Re-fetch the potentially updated auth info from the user object
-	googleAccountAuth = user.CalendarAccounts[accountKey].OAuth2CalendarAuth
-	if googleAccountAuth == nil || googleAccountAuth.RefreshToken == "" {
-		// If refresh failed or token is still missing
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh Google authentication token"})
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
 		return
 	}
 
+	options := conferencing.MeetingOptions{
+		Title: event.Name,
+		Link:  utils.Coalesce(payload.Link),
+	}
+	if payload.Title != nil {
+		options.Title = *payload.Title
+	}
+	if payload.StartDate != nil && payload.EndDate != nil {
+		options.StartTime, options.EndTime = payload.StartDate.Time(), payload.EndDate.Time()
+	} else if event.ScheduledEvent != nil {
+		options.StartTime, options.EndTime = event.ScheduledEvent.StartDate.Time(), event.ScheduledEvent.EndDate.Time()
+	}
+
+	createMeetLink(c, event, payload.Provider, options)
+}
 
-	// --- Create Google Calendar Service Client ---
-	calendarService, err := google_api.GetCalendarService(googleAccountAuth.RefreshToken)
-	if err != nil {
-		logger.StdErr.Printf("Error creating calendar service for user %s: %v", user.Id.Hex(), err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not connect to Google Calendar"})
+// @Summary Removes the video conferencing link from an event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/conference [delete]
+func deleteConference(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
 		return
 	}
 
-	// --- Parse Start/End Times ---
-	startTime, err := time.Parse(time.RFC3339, req.StartDateTime)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date/time format: " + err.Error()})
+	if !authorizeEventRole(c, event, models.EditorRole) {
 		return
 	}
-	endTime := startTime.Add(time.Duration(req.DurationMinutes) * time.Minute)
 
-	// --- Prepare Calendar Event Resource ---
-	calendarEvent := &googlecalendar.Event{
-		Summary: req.Title,
-		Start: &googlecalendar.EventDateTime{
-			DateTime: startTime.Format(time.RFC3339),
-			// Add TimeZone if available/needed
-		},
-		End: &googlecalendar.EventDateTime{
-			DateTime: endTime.Format(time.RFC3339),
-			// Add TimeZone if available/needed
-		},
-		ConferenceData: &googlecalendar.ConferenceData{
-			CreateRequest: &googlecalendar.CreateConferenceRequest{
-				RequestId: uuid.New().String(), // Generate unique request ID
-				ConferenceSolutionKey: &googlecalendar.ConferenceSolutionKey{
-					Type: "hangoutsMeet",
-				},
-			},
-		},
+	if err := db.RemoveMeetLinkFromEvent(event.Id); err != nil {
+		logger.StdErr.Panicln(err)
 	}
+	deleteMeeting(event)
 
-	// --- Insert Event into Google Calendar ---
-	// Use "primary" calendar ID for the user's main calendar
-	calendarId := "primary"
-	// conferenceDataVersion=1 is crucial to get the Meet link back
-	createdEvent, err := calendarService.Events.Insert(calendarId, calendarEvent).ConferenceDataVersion(1).Do()
-	if err != nil {
-		logger.StdErr.Printf("Error inserting event into Google Calendar for user %s: %v", user.Id.Hex(), err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Google Calendar event: " + err.Error()})
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// Creates a meeting link for the event with the given provider, saves it to the event, and responds with the link
+func createMeetLink(c *gin.Context, event *models.Event, providerName models.ConferencingProvider, options conferencing.MeetingOptions) {
+	user := utils.GetAuthUser(c)
+	if !authorizeEventRole(c, event, models.EditorRole) {
 		return
 	}
 
-	// --- Extract Meet Link ---
-	if createdEvent.ConferenceData == nil || createdEvent.HangoutLink == "" {
-		logger.StdErr.Printf("Google Calendar event created but missing HangoutLink for user %s, Event ID: %s", user.Id.Hex(), createdEvent.Id)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve Google Meet link after creating event"})
+	provider, ok := conferencing.GetProvider(providerName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.UnknownMeetProvider})
+		return
+	}
+
+	var startTime, endTime string
+	if provider.RequiresTime() {
+		if !options.EndTime.After(options.StartTime) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidTimeRange})
+			return
+		}
+		startTime, endTime = options.StartTime.Format(time.RFC3339), options.EndTime.Format(time.RFC3339)
+	}
+
+	options.User = user
+	meeting, err := provider.CreateMeeting(options)
+	if err != nil {
+		logger.StdErr.Printf("Error creating %s meeting for user %s: %v", providerName, user.Id.Hex(), err)
+		switch {
+		case errors.Is(err, conferencing.ErrAccountNotConnected):
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.CalendarNotFound})
+		case errors.Is(err, conferencing.ErrInvalidLink):
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidMeetingLink})
+		case errors.Is(err, microsoftgraph.ErrOnlineMeetingsNotSupported):
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.MeetingsNotSupported})
+		case errors.Is(err, conferencing.ErrInsufficientScope):
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.CalendarNeedsReauth})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create meeting: " + err.Error()})
+		}
 		return
 	}
 
-	meetLink := createdEvent.HangoutLink
-	logger.StdOut.Printf("Successfully created Google Meet link for user %s: %s", user.Id.Hex(), meetLink)
+	if err := db.AddMeetLinkToEvent(event.Id.Hex(), providerName, meeting.Link, meeting.CalendarEvent, startTime, endTime); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	// The new link replaces the previous meeting
+	deleteMeeting(event)
 
 	c.JSON(http.StatusOK, gin.H{
-		"meetLink": meetLink,
-		"startTime": startTime.Format(time.RFC3339), // Return consistent format
-		"endTime": endTime.Format(time.RFC3339),     // Return consistent format
+		"meetLink":     meeting.Link,
+		"meetProvider": providerName,
+		"startTime":    startTime,
+		"endTime":      endTime,
 	})
 }
 
-// Helper function to find a response by userId
-func findResponse(responses []models.EventResponse, userId string) (int, *models.Response) {
-	for i, resp := range responses {
-		if resp.UserId == userId {
-			return i, resp.Response
-		}
+// Removes the calendar event that the event's current meeting was created as, if any. Failures are only logged, since the
+// link has already been removed from the event
+func deleteMeeting(event *models.Event) {
+	if event.MeetCalendarEvent == nil || event.MeetProvider == nil {
+		return
 	}
-	return -1, nil
-}
 
-// Helper function to get all responses as a map (for backward compatibility)
-func getResponsesMap(responses []models.EventResponse) map[string]*models.Response {
-	result := make(map[string]*models.Response)
-	for _, resp := range responses {
-		result[resp.UserId] = resp.Response
+	provider, ok := conferencing.GetProvider(*event.MeetProvider)
+	if !ok {
+		return
 	}
-	return result
+	user := db.GetUserById(event.MeetCalendarEvent.UserId.Hex())
+	if err := provider.DeleteMeeting(user, event.MeetCalendarEvent); err != nil {
+		logger.StdErr.Printf("Error deleting %s meeting of event %s: %v", *event.MeetProvider, event.Id.Hex(), err)
+	}
+}
+
+// @Summary Schedules an event at the chosen time and notifies everyone involved
+// @Description Sets the scheduled event, records which respondents are expected to attend, and emails every respondent, remindee, and attendee with an email address.
+// @Description If calendarAccountKey and calendarId are given, the event is also created in that calendar of the owner. Rescheduling updates the previously created calendar event.
+// @Description Fails with calendar-needs-reauth if the calendar account was connected with read only access, in which case it has to be reconnected
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{startDate=string,endDate=string,respondents=[]string,calendarAccountKey=string,calendarId=string} true "Object containing the chosen time, optionally the ids of the respondents expected to attend, and optionally the calendar to add the event to"
+// @Success 200 {object} models.CalendarEvent
+// @Router /events/{eventId}/schedule [post]
+func scheduleEvent(c *gin.Context) {
+	payload := struct {
+		StartDate primitive.DateTime `json:"startDate" binding:"required"`
+		EndDate   primitive.DateTime `json:"endDate" binding:"required"`
+
+		// Defaults to everyone available or available if needed during the chosen time
+		Respondents *[]string `json:"respondents"`
+
+		// The owner's calendar to add the scheduled event to
+		CalendarAccountKey *string `json:"calendarAccountKey"`
+		CalendarId         *string `json:"calendarId"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	if payload.EndDate <= payload.StartDate {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidTimeRange})
+		return
+	}
+
+	eventId := c.Param("eventId")
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	// Make sure user has permission to schedule this event
+	user := utils.GetAuthUser(c)
+	if !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	// Determine who is expected to attend
+	var expectedRespondents []string
+	if payload.Respondents != nil {
+		expectedRespondents = *payload.Respondents
+	} else {
+		startDate := payload.StartDate.Time()
+		slot := availability.GetTimeSlot(event, startDate, payload.EndDate.Time().Sub(startDate))
+		expectedRespondents = append(slot.Available, slot.IfNeeded...)
+	}
+
+	event.ScheduledEvent = &models.CalendarEvent{
+		Summary:   event.Name,
+		StartDate: payload.StartDate,
+		EndDate:   payload.EndDate,
+	}
+	event.ExpectedRespondents = &expectedRespondents
+	event.ScheduleSequence++
+
+	// Write the scheduled event to the user's calendar, or update the calendar event it was previously written to
+	calendarUser, calendarAccountKey, calendarId := getCalendarUser(event, user), event.CalendarAccountKey, event.CalendarId
+	if payload.CalendarAccountKey != nil {
+		calendarUser, calendarAccountKey, calendarId = user, *payload.CalendarAccountKey, utils.Coalesce(payload.CalendarId)
+
+		account, ok := user.CalendarAccounts[calendarAccountKey]
+		if !ok || len(calendarId) == 0 || (account.SubCalendars != nil && !hasSubCalendar(*account.SubCalendars, calendarId)) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.CalendarNotFound})
+			return
+		}
+	}
+	if len(calendarAccountKey) > 0 {
+		if err := writeScheduledEventToCalendar(calendarUser, event, calendarAccountKey, calendarId); err != nil {
+			logger.StdErr.Println(err)
+			if errors.Is(err, calendar.ErrReadOnlyCalendar) {
+				c.JSON(http.StatusBadRequest, responses.Error{Error: errs.CalendarReadOnly})
+			} else if errors.Is(err, calendar.ErrInsufficientScope) {
+				c.JSON(http.StatusForbidden, responses.Error{Error: errs.CalendarNeedsReauth})
+			} else {
+				c.JSON(http.StatusInternalServerError, responses.Error{Error: errs.CalendarWriteFailed})
+			}
+			return
+		}
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{
+			"scheduledEvent":      event.ScheduledEvent,
+			"expectedRespondents": event.ExpectedRespondents,
+			"scheduleSequence":    event.ScheduleSequence,
+			"calendarEventId":     event.CalendarEventId,
+			"calendarAccountKey":  event.CalendarAccountKey,
+			"calendarId":          event.CalendarId,
+			"calendarUserId":      event.CalendarUserId,
+		},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventScheduled})
+	recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.EventScheduledAction})
+
+	// Send scheduled emails with a calendar invite asynchronously
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		emails := getParticipantEmails(event)
+		invite, err := getScheduledEventInvite(event, user, emails, ics.REQUEST)
+		if err != nil {
+			logger.StdErr.Println(err)
+			return
+		}
+
+		eventScheduledEmailId := 15
+		for _, email := range emails {
+			listmonk.SendEmailAddSubscriberIfNotExist(email, eventScheduledEmailId, bson.M{
+				"eventName": event.Name,
+				"ownerName": user.FirstName,
+				"startDate": payload.StartDate.Time().Format(time.RFC3339),
+				"endDate":   payload.EndDate.Time().Format(time.RFC3339),
+				"eventUrl":  ics.GetEventUrl(event),
+			}, invite)
+		}
+	}()
+
+	c.JSON(http.StatusOK, event.ScheduledEvent)
+}
+
+// @Summary Clears the scheduled time of an event
+// @Description Also removes the scheduled event from the owner's calendar if it was added to one
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/schedule [delete]
+func unscheduleEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	// Make sure user has permission to unschedule this event
+	user := utils.GetAuthUser(c)
+	if !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	// Remove the scheduled event from the calendar it was written to
+	if len(event.CalendarEventId) > 0 {
+		if err := deleteScheduledEventFromCalendar(getCalendarUser(event, user), event); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$unset": bson.M{
+			"scheduledEvent":      "",
+			"expectedRespondents": "",
+			"calendarEventId":     "",
+			"calendarAccountKey":  "",
+			"calendarId":          "",
+			"calendarUserId":      "",
+		},
+		"$inc": bson.M{"scheduleSequence": 1, "version": 1},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventUnscheduled})
+	recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.EventUnscheduledAction})
+
+	// Send cancellation emails so the invite is removed from everyone's calendar
+	if event.ScheduledEvent != nil {
+		event.ScheduleSequence++
+
+		go func() {
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}()
+
+			emails := getParticipantEmails(event)
+			cancellation, err := getScheduledEventInvite(event, user, emails, ics.CANCEL)
+			if err != nil {
+				logger.StdErr.Println(err)
+				return
+			}
+
+			eventUnscheduledEmailId := 16
+			for _, email := range emails {
+				listmonk.SendEmailAddSubscriberIfNotExist(email, eventUnscheduledEmailId, bson.M{
+					"eventName": event.Name,
+					"ownerName": user.FirstName,
+					"eventUrl":  ics.GetEventUrl(event),
+				}, cancellation)
+			}
+		}()
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Makes the responses to an event read-only
+// @Description Respondents can't change or delete their responses while they are locked, except for the unlocked respondents. Calling this again replaces the unlocked respondents
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{unlockedRespondents=[]string} false "Object containing the ids (keys of the responses map) of the respondents who can still change their responses"
+// @Success 200
+// @Router /events/{eventId}/lock [post]
+func lockResponses(c *gin.Context) {
+	payload := struct {
+		UnlockedRespondents []string `json:"unlockedRespondents"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if err := c.Bind(&payload); err != nil {
+			return
+		}
+	}
+	if payload.UnlockedRespondents == nil {
+		payload.UnlockedRespondents = make([]string, 0)
+	}
+
+	setResponsesLocked(c, true, payload.UnlockedRespondents)
+}
+
+// @Summary Lets respondents change their responses to an event again
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/lock [delete]
+func unlockResponses(c *gin.Context) {
+	setResponsesLocked(c, false, make([]string, 0))
+}
+
+func setResponsesLocked(c *gin.Context, locked bool, unlockedRespondents []string) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	_, err := saveEventUpdate(event, func(event *models.Event) bool {
+		event.ResponsesLocked = &locked
+		event.UnlockedRespondents = &unlockedRespondents
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventEdited})
+
+	historyAction := models.ResponsesUnlockedAction
+	if locked {
+		historyAction = models.ResponsesLockedAction
+	}
+	recordEventHistory(c, event, "", models.EventHistoryEntry{Action: historyAction})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Gets the history of changes made to an event, from newest to oldest
+// @Description Only owners can view the history. Guest events can be viewed with the edit token in the X-Edit-Token header
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.EventHistoryEntry
+// @Router /events/{eventId}/history [get]
+func getEventHistory(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.OwnerRole) {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetEventHistory(event.Id))
+}
+
+// @Summary Downloads the scheduled time of an event as an iCalendar file
+// @Tags events
+// @Produce text/calendar
+// @Param eventId path string true "Event ID"
+// @Success 200 {string} string "The .ics file"
+// @Router /events/{eventId}/ics [get]
+func getEventIcs(c *gin.Context) {
+	eventId := c.Param("eventId")
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	if event.ScheduledEvent == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotScheduled})
+		return
+	}
+
+	var organizer *ics.Participant
+	if owner := db.GetUserById(event.OwnerId.Hex()); owner != nil {
+		organizer = &ics.Participant{Name: fmt.Sprintf("%s %s", owner.FirstName, owner.LastName), Email: owner.Email}
+	}
+
+	data, err := ics.GetScheduledEventIcs(event, ics.ScheduledEventOptions{
+		Method:    ics.PUBLISH,
+		Organizer: organizer,
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ics"`, event.GetId()))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// Returns the iTIP message for the scheduled event as an email attachment
+func getScheduledEventInvite(event *models.Event, organizer *models.User, emails []string, method ics.Method) (listmonk.Attachment, error) {
+	attendees := utils.Map(emails, func(email string) ics.Participant { return ics.Participant{Email: email} })
+	data, err := ics.GetScheduledEventIcs(event, ics.ScheduledEventOptions{
+		Method:    method,
+		Organizer: &ics.Participant{Name: fmt.Sprintf("%s %s", organizer.FirstName, organizer.LastName), Email: organizer.Email},
+		Attendees: attendees,
+	})
+	if err != nil {
+		return listmonk.Attachment{}, err
+	}
+
+	return listmonk.Attachment{
+		FileName:    "invite.ics",
+		ContentType: fmt.Sprintf("text/calendar; charset=utf-8; method=%s", method),
+		Data:        data,
+	}, nil
+}
+
+// Creates the scheduled event in the given calendar of the owner, or updates it if it was already written to that calendar.
+// If it was previously written to a different calendar, it is moved to the given calendar
+func writeScheduledEventToCalendar(user *models.User, event *models.Event, calendarAccountKey string, calendarId string) error {
+	details := calendar.CalendarEventDetails{
+		UID:         ics.GetUID(event),
+		Summary:     event.Name,
+		Description: strings.TrimSpace(fmt.Sprintf("%s\n\n%s", utils.Coalesce(event.Description), ics.GetEventUrl(event))),
+		Location:    utils.Coalesce(event.MeetLink),
+		StartDate:   event.ScheduledEvent.StartDate.Time(),
+		EndDate:     event.ScheduledEvent.EndDate.Time(),
+	}
+
+	if len(event.CalendarEventId) > 0 {
+		previousUser := getCalendarUser(event, user)
+		if previousUser.Id == user.Id && event.CalendarAccountKey == calendarAccountKey && event.CalendarId == calendarId {
+			calendarProvider := getOwnerCalendarProvider(user, calendarAccountKey)
+			if calendarProvider != nil {
+				return calendarProvider.UpdateEvent(calendarId, event.CalendarEventId, details)
+			}
+		} else if err := deleteScheduledEventFromCalendar(previousUser, event); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+
+	calendarProvider := getOwnerCalendarProvider(user, calendarAccountKey)
+	if calendarProvider == nil {
+		// The calendar account the event was previously written to has since been removed
+		event.CalendarEventId, event.CalendarAccountKey, event.CalendarId, event.CalendarUserId = "", "", "", primitive.NilObjectID
+		return nil
+	}
+
+	calendarEventId, err := calendarProvider.CreateEvent(calendarId, details)
+	if err != nil {
+		return err
+	}
+
+	event.CalendarEventId, event.CalendarAccountKey, event.CalendarId, event.CalendarUserId = calendarEventId, calendarAccountKey, calendarId, user.Id
+	return nil
+}
+
+// Returns the user whose calendar the scheduled event was written to, which is the owner for events scheduled
+// before collaborators could schedule them. Falls back to the given user if that user no longer exists
+func getCalendarUser(event *models.Event, user *models.User) *models.User {
+	calendarUserId := event.CalendarUserId
+	if calendarUserId == primitive.NilObjectID {
+		calendarUserId = event.OwnerId
+	}
+	if calendarUserId == user.Id {
+		return user
+	}
+	if calendarUser := db.GetUserById(calendarUserId.Hex()); calendarUser != nil {
+		return calendarUser
+	}
+	return user
+}
+
+// Deletes the scheduled event from the calendar of the given user that it was written to
+func deleteScheduledEventFromCalendar(user *models.User, event *models.Event) error {
+	calendarProvider := getOwnerCalendarProvider(user, event.CalendarAccountKey)
+	if calendarProvider == nil {
+		return nil
+	}
+
+	return calendarProvider.DeleteEvent(event.CalendarId, event.CalendarEventId)
+}
+
+// Returns the calendar provider of the given calendar account of the user, refreshing its access token if necessary.
+// Returns nil if the user doesn't have the calendar account
+func getOwnerCalendarProvider(user *models.User, calendarAccountKey string) calendar.CalendarProvider {
+	if _, ok := user.CalendarAccounts[calendarAccountKey]; !ok {
+		return nil
+	}
+
+	auth.RefreshUserTokenIfNecessary(user, models.Set[string]{calendarAccountKey: {}})
+	return calendar.GetCalendarProvider(user.CalendarAccounts[calendarAccountKey])
+}
+
+func hasSubCalendar(subCalendars map[string]models.SubCalendar, calendarId string) bool {
+	_, ok := subCalendars[calendarId]
+	return ok
+}
+
+// Maximum length of the date range of a recurring meeting poll
+const maxRecurrenceLength = 366 * 24 * time.Hour
+
+// Returns whether the recurrence can be set on an event of the given type
+func isValidRecurrence(eventType models.EventType, recurrence models.Recurrence) bool {
+	length := recurrence.EndDate.Time().Sub(recurrence.StartDate.Time())
+	return eventType == models.DOW && length > 0 && length <= maxRecurrenceLength && recurrence.Interval >= 0
+}
+
+// Whether the custom questions are valid. Gives new questions an id, while edited questions keep theirs so that
+// the answers to them are kept
+func prepareQuestions(questions *[]models.Question) bool {
+	ids := make(models.Set[primitive.ObjectID])
+	for i := range utils.Coalesce(questions) {
+		question := &(*questions)[i]
+		if !question.IsValid() {
+			return false
+		}
+
+		if question.Id == primitive.NilObjectID {
+			question.Id = primitive.NewObjectID()
+		}
+		if _, ok := ids[question.Id]; ok {
+			return false
+		}
+		ids[question.Id] = struct{}{}
+	}
+
+	return true
+}
+
+// Returns a map mapping the ids of signed in respondents to the events in their enabled calendars during the given time range
+func getRespondentsCalendarEvents(event *models.Event, timeMin time.Time, timeMax time.Time) map[string][]models.CalendarEvent {
+	type respondentCalendarEvents struct {
+		UserId string
+		Events []models.CalendarEvent
+	}
+
+	numCalendarEventsRequests := 0
+	calendarEventsChan := make(chan respondentCalendarEvents)
+	for _, eventResponse := range event.ResponsesList {
+		user := db.GetUserById(eventResponse.UserId)
+		if user == nil || eventResponse.Response == nil {
+			continue
+		}
+
+		// Use the calendars the respondent chose for this event if they did, otherwise their enabled calendars
+		enabledCalendarIds := make(map[string]models.Set[string])
+		if eventResponse.Response.EnabledCalendars != nil {
+			for calendarAccountKey, calendarIds := range *eventResponse.Response.EnabledCalendars {
+				enabledCalendarIds[calendarAccountKey] = utils.ArrayToSet(calendarIds)
+			}
+		} else {
+			for calendarAccountKey, account := range user.CalendarAccounts {
+				if !utils.Coalesce(account.Enabled) {
+					continue
+				}
+				enabledCalendarIds[calendarAccountKey] = make(models.Set[string])
+				for calendarId, subCalendar := range utils.Coalesce(account.SubCalendars) {
+					if utils.Coalesce(subCalendar.Enabled) {
+						enabledCalendarIds[calendarAccountKey][calendarId] = struct{}{}
+					}
+				}
+			}
+		}
+		if len(enabledCalendarIds) == 0 {
+			continue
+		}
+
+		numCalendarEventsRequests++
+		go func(userId string, user *models.User) {
+			result := respondentCalendarEvents{UserId: userId, Events: make([]models.CalendarEvent, 0)}
+
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+				calendarEventsChan <- result
+			}()
+
+			accounts := make(models.Set[string])
+			for calendarAccountKey := range enabledCalendarIds {
+				accounts[calendarAccountKey] = struct{}{}
+			}
+
+			calendarEvents, _ := calendar.GetUsersCalendarEvents(user, accounts, timeMin, timeMax)
+			for calendarAccountKey, events := range calendarEvents {
+				for _, calendarEvent := range events.CalendarEvents {
+					if _, ok := enabledCalendarIds[calendarAccountKey][calendarEvent.CalendarId]; ok {
+						result.Events = append(result.Events, calendarEvent)
+					}
+				}
+			}
+		}(eventResponse.UserId, user)
+	}
+
+	userIdToCalendarEvents := make(map[string][]models.CalendarEvent)
+	for i := 0; i < numCalendarEventsRequests; i++ {
+		result := <-calendarEventsChan
+		userIdToCalendarEvents[result.UserId] = result.Events
+	}
+
+	return userIdToCalendarEvents
+}
+
+// Returns the edited remindees with whether they responded taken from the latest remindees
+func mergeRemindees(edited *[]models.Remindee, latest *[]models.Remindee) *[]models.Remindee {
+	if edited == nil {
+		return latest
+	}
+
+	merged := make([]models.Remindee, len(*edited))
+	copy(merged, *edited)
+	for i := range merged {
+		index := utils.Find(utils.Coalesce(latest), func(r models.Remindee) bool { return r.Email == merged[i].Email })
+		if index != -1 {
+			merged[i].Responded = (*latest)[index].Responded
+		}
+	}
+	return &merged
+}
+
+// Returns the edited attendees with whether they declined taken from the latest attendees
+func mergeAttendees(edited *[]models.Attendee, latest *[]models.Attendee) *[]models.Attendee {
+	if edited == nil {
+		return latest
+	}
+
+	merged := make([]models.Attendee, len(*edited))
+	copy(merged, *edited)
+	for i := range merged {
+		index := utils.Find(utils.Coalesce(latest), func(a models.Attendee) bool { return strings.EqualFold(a.Email, merged[i].Email) })
+		if index != -1 {
+			merged[i].Declined = (*latest)[index].Declined
+		}
+	}
+	return &merged
+}
+
+// Number of times an event update is attempted when someone else keeps updating the event at the same time
+const maxEventUpdateAttempts = 5
+
+// Applies update to the event and saves it. If someone else updated the event since it was fetched, update is applied
+// again to the latest version of the event, so update should only modify the event and not have any other side effects.
+// update returns false if there is nothing to save. Returns whether the event was saved
+func saveEventUpdate(event *models.Event, update func(event *models.Event) bool) (bool, error) {
+	for attempt := 0; attempt < maxEventUpdateAttempts; attempt++ {
+		if attempt > 0 {
+			latestEvent := db.GetEventById(event.Id.Hex())
+			if latestEvent == nil {
+				return false, db.ErrEventVersionConflict
+			}
+			*event = *latestEvent
+		}
+
+		if !update(event) {
+			return false, nil
+		}
+
+		err := db.UpdateEvent(event)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, db.ErrEventVersionConflict) {
+			return false, err
+		}
+	}
+
+	return false, db.ErrEventVersionConflict
+}
+
+// Responds with 403 and returns false if the signed in user doesn't have at least the given role on the event.
+// Presenting the edit token of a guest event in the X-Edit-Token header grants every role
+func authorizeEventRole(c *gin.Context, event *models.Event, role models.CollaboratorRole) bool {
+	if !event.HasRole(getSessionUserId(c), role) && !hasEditToken(c, event) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOwner})
+		return false
+	}
+
+	return true
+}
+
+// Deletes the pending reminder email tasks of the remindees who haven't responded yet
+func cancelReminders(event *models.Event) {
+	for _, remindee := range utils.Coalesce(event.Remindees) {
+		if !utils.Coalesce(remindee.Responded) {
+			for _, taskId := range remindee.TaskIds {
+				gcloud.DeleteEmailTask(taskId)
+			}
+		}
+	}
+}
+
+// Appends the entries to the history of the event, attributed to the signed in user, or to the guest with the given name.
+// Failing to record the history doesn't fail the request, since the change itself was already saved
+func recordEventHistory(c *gin.Context, event *models.Event, guestName string, entries ...models.EventHistoryEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	actorId := getSessionUserId(c)
+	actorName := guestName
+	if actorId != primitive.NilObjectID {
+		if user := db.GetUserById(actorId.Hex()); user != nil {
+			actorName = strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+		}
+	}
+
+	timestamp := primitive.NewDateTimeFromTime(time.Now())
+	for i := range entries {
+		entries[i].EventId = event.Id
+		entries[i].Timestamp = timestamp
+		entries[i].ActorId = actorId
+		entries[i].ActorName = actorName
+	}
+
+	if err := db.InsertEventHistoryEntries(entries); err != nil {
+		logger.StdErr.Println(err)
+	}
+}
+
+// The fields that editEvent changes, by their json names, along with how to get their values for comparison
+var editableEventFields = []struct {
+	name  string
+	value func(event *models.Event) interface{}
+}{
+	{"name", func(e *models.Event) interface{} { return e.Name }},
+	{"description", func(e *models.Event) interface{} { return utils.Coalesce(e.Description) }},
+	{"duration", func(e *models.Event) interface{} { return utils.Coalesce(e.Duration) }},
+	{"dates", func(e *models.Event) interface{} { return e.Dates }},
+	{"type", func(e *models.Event) interface{} { return e.Type }},
+	{"signUpBlocks", func(e *models.Event) interface{} { return utils.Coalesce(e.SignUpBlocks) }},
+	{"startOnMonday", func(e *models.Event) interface{} { return utils.Coalesce(e.StartOnMonday) }},
+	{"notificationsEnabled", func(e *models.Event) interface{} { return utils.Coalesce(e.NotificationsEnabled) }},
+	{"blindAvailabilityEnabled", func(e *models.Event) interface{} { return utils.Coalesce(e.BlindAvailabilityEnabled) }},
+	{"daysOnly", func(e *models.Event) interface{} { return utils.Coalesce(e.DaysOnly) }},
+	{"sendEmailAfterXResponses", func(e *models.Event) interface{} { return utils.Coalesce(e.SendEmailAfterXResponses) }},
+	{"collectEmails", func(e *models.Event) interface{} { return utils.Coalesce(e.CollectEmails) }},
+	{"questions", func(e *models.Event) interface{} {
+		// No questions and an empty list of questions are the same
+		if len(utils.Coalesce(e.Questions)) == 0 {
+			return nil
+		}
+		return *e.Questions
+	}},
+	{"recurrence", func(e *models.Event) interface{} { return e.Recurrence }},
+	{"timeZone", func(e *models.Event) interface{} { return utils.Coalesce(e.TimeZone) }},
+	{"closesAt", func(e *models.Event) interface{} { return utils.Coalesce(e.ClosesAt) }},
+	{"remindees", func(e *models.Event) interface{} {
+		return utils.Map(utils.Coalesce(e.Remindees), func(r models.Remindee) string { return r.Email })
+	}},
+}
+
+// Returns the json names of the editable fields that differ between the original and the edited event.
+// Attendees aren't included since adding and removing them is recorded separately
+func getEditedFields(original *models.Event, edited *models.Event) []string {
+	fields := make([]string, 0)
+	for _, field := range editableEventFields {
+		if !reflect.DeepEqual(field.value(original), field.value(edited)) {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
+
+// Returns the id of the signed in user, or the nil object id if nobody is signed in
+func getSessionUserId(c *gin.Context) primitive.ObjectID {
+	userId, _ := sessions.Default(c).Get("userId").(string)
+	objectId, _ := primitive.ObjectIDFromHex(userId)
+	return objectId
+}
+
+// Whether the request has the edit token of the event in the X-Edit-Token header
+func hasEditToken(c *gin.Context, event *models.Event) bool {
+	token := c.GetHeader("X-Edit-Token")
+	if event.EditToken == nil || len(token) == 0 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(*event.EditToken)) == 1
+}
+
+// Helper function to find a response by userId
+func findResponse(responses []models.EventResponse, userId string) (int, *models.Response) {
+	for i, resp := range responses {
+		if resp.UserId == userId {
+			return i, resp.Response
+		}
+	}
+	return -1, nil
+}
+
+// Helper function to get all responses as a map (for backward compatibility)
+func getResponsesMap(responses []models.EventResponse) map[string]*models.Response {
+	result := make(map[string]*models.Response)
+	for _, resp := range responses {
+		result[resp.UserId] = resp.Response
+	}
+	return result
+}
+
+// Returns the deduplicated emails of everyone who left an email on the event, i.e. respondents, remindees, and attendees who haven't declined
+func getParticipantEmails(event *models.Event) []string {
+	emails := make([]string, 0)
+	seen := make(models.Set[string])
+	addEmail := func(email string) {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if len(key) == 0 {
+			return
+		}
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		emails = append(emails, email)
+	}
+
+	for _, eventResponse := range event.ResponsesList {
+		if eventResponse.Response == nil {
+			continue
+		}
+		if len(eventResponse.Response.Email) > 0 {
+			addEmail(eventResponse.Response.Email)
+		} else if user := db.GetUserById(eventResponse.UserId); user != nil {
+			addEmail(user.Email)
+		}
+	}
+	for _, response := range event.SignUpResponses {
+		if len(response.Email) > 0 {
+			addEmail(response.Email)
+		} else if user := db.GetUserById(response.UserId.Hex()); user != nil {
+			addEmail(user.Email)
+		}
+	}
+	for _, remindee := range utils.Coalesce(event.Remindees) {
+		addEmail(remindee.Email)
+	}
+	for _, attendee := range utils.Coalesce(event.Attendees) {
+		if !utils.Coalesce(attendee.Declined) {
+			addEmail(attendee.Email)
+		}
+	}
+
+	return emails
 }
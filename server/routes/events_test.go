@@ -0,0 +1,911 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Connects the db package to a throwaway database on the local Mongo, skipping the test if there is none
+func initTestDb(t *testing.T) {
+	mongoURI := os.Getenv("MONGODB_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI).SetServerSelectionTimeout(2*time.Second))
+	if err != nil {
+		t.Skipf("mongo is not available: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("mongo is not available: %v", err)
+	}
+
+	testDb := client.Database(fmt.Sprintf("schej-it-test-%d", time.Now().UnixNano()))
+	db.EventsCollection = testDb.Collection("events")
+	db.EventResponsesCollection = testDb.Collection("eventresponses")
+	db.UsersCollection = testDb.Collection("users")
+	db.EventHistoryCollection = testDb.Collection("eventhistory")
+	db.EventCommentsCollection = testDb.Collection("eventcomments")
+	db.PollVotesCollection = testDb.Collection("pollvotes")
+
+	_, err = db.EventResponsesCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "eventId", Value: 1}, {Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LISTMONK_ENABLED", "false")
+	t.Cleanup(func() {
+		testDb.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+}
+
+// Header that signs a test request in as the user with the given id, since tests can't go through the sign in flow
+const testUserIdHeader = "X-Test-User-Id"
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("secret"))))
+	router.Use(func(c *gin.Context) {
+		if userId := c.GetHeader(testUserIdHeader); len(userId) > 0 {
+			sessions.Default(c).Set("userId", userId)
+		}
+	})
+	InitEvents(router.Group("/api"))
+	InitTasks(router.Group("/api"))
+	return router
+}
+
+func insertTestEvent(t *testing.T, event models.Event) *models.Event {
+	event.Id = primitive.NewObjectID()
+	if _, err := db.EventsCollection.InsertOne(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	return &event
+}
+
+func insertTestUser(t *testing.T, email string) *models.User {
+	user := models.User{Id: primitive.NewObjectID(), Email: email, FirstName: "Test"}
+	if _, err := db.UsersCollection.InsertOne(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+// Sends a request with the body encoded as json, and the edit token in the X-Edit-Token header unless it is empty
+func sendRequest(router http.Handler, method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	var data io.Reader
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		data = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, data)
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("X-Edit-Token", token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// Returns a handler that sends requests to the router with the given header set
+func withHeader(router http.Handler, key string, value string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Header.Set(key, value)
+		router.ServeHTTP(w, req)
+	})
+}
+
+// Returns a handler that sends requests to the router signed in as the given user
+func signedInAs(router http.Handler, user *models.User) http.Handler {
+	return withHeader(router, testUserIdHeader, user.Id.Hex())
+}
+
+// Sends the requests in parallel and returns the status codes of the responses
+func sendInParallel(router http.Handler, method string, path string, bodies []interface{}) []int {
+	return runInParallel(len(bodies), func(i int) int {
+		return sendRequest(router, method, path, bodies[i], "").Code
+	})
+}
+
+// Calls send for each index from 0 to n - 1 in parallel and returns the status codes it returns
+func runInParallel(n int, send func(i int) int) []int {
+	statuses := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = send(i)
+		}(i)
+	}
+	wg.Wait()
+	return statuses
+}
+
+func TestConcurrentEventResponses(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	numGuests := 20
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	remindees := make([]models.Remindee, maxEventUpdateAttempts)
+	for i := range remindees {
+		remindees[i] = models.Remindee{Email: fmt.Sprintf("remindee%d@example.com", i), Responded: utils.FalsePtr()}
+	}
+	event := insertTestEvent(t, models.Event{
+		Name:      "Concurrency test",
+		Type:      models.SPECIFIC_DATES,
+		Dates:     []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		Remindees: &remindees,
+	})
+
+	// Respond as every guest and mark every remindee as responded at the same time
+	responseBodies := make([]interface{}, numGuests)
+	for i := range responseBodies {
+		responseBodies[i] = gin.H{
+			"guest":        true,
+			"name":         fmt.Sprintf("Guest %d", i),
+			"availability": []primitive.DateTime{primitive.NewDateTimeFromTime(start.Add(time.Duration(i) * 15 * time.Minute))},
+			"ifNeeded":     []primitive.DateTime{},
+		}
+	}
+	respondedBodies := make([]interface{}, len(remindees))
+	for i, remindee := range remindees {
+		respondedBodies[i] = gin.H{"email": remindee.Email}
+	}
+
+	var wg sync.WaitGroup
+	var responseStatuses, respondedStatuses []int
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		responseStatuses = sendInParallel(router, http.MethodPost, fmt.Sprintf("/api/events/%s/response", event.Id.Hex()), responseBodies)
+	}()
+	go func() {
+		defer wg.Done()
+		respondedStatuses = sendInParallel(router, http.MethodPost, fmt.Sprintf("/api/events/%s/responded", event.Id.Hex()), respondedBodies)
+	}()
+	wg.Wait()
+
+	for _, status := range append(responseStatuses, respondedStatuses...) {
+		if status != http.StatusOK {
+			t.Errorf("expected every request to succeed, got status %d", status)
+		}
+	}
+
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	if len(updatedEvent.ResponsesList) != numGuests {
+		t.Errorf("expected %d responses, got %d", numGuests, len(updatedEvent.ResponsesList))
+	}
+	for _, remindee := range utils.Coalesce(updatedEvent.Remindees) {
+		if !utils.Coalesce(remindee.Responded) {
+			t.Errorf("expected %s to have responded", remindee.Email)
+		}
+	}
+	if updatedEvent.Version != len(remindees) {
+		t.Errorf("expected the event to be updated once per remindee, got version %d", updatedEvent.Version)
+	}
+}
+
+func TestConcurrentSignUpResponses(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	numGuests := 20
+	blockId := primitive.NewObjectID()
+	event := insertTestEvent(t, models.Event{
+		Name:            "Sign up concurrency test",
+		Type:            models.SPECIFIC_DATES,
+		IsSignUpForm:    utils.TruePtr(),
+		SignUpBlocks:    &[]models.SignUpBlock{{Id: blockId, Name: "Block"}},
+		SignUpResponses: make(map[string]*models.SignUpResponse),
+	})
+
+	bodies := make([]interface{}, numGuests)
+	for i := range bodies {
+		bodies[i] = gin.H{
+			"guest":          true,
+			"name":           fmt.Sprintf("Guest.%d", i),
+			"signUpBlockIds": []primitive.ObjectID{blockId},
+		}
+	}
+	for _, status := range sendInParallel(router, http.MethodPost, fmt.Sprintf("/api/events/%s/response", event.Id.Hex()), bodies) {
+		if status != http.StatusOK {
+			t.Errorf("expected every request to succeed, got status %d", status)
+		}
+	}
+
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	if len(updatedEvent.SignUpResponses) != numGuests {
+		t.Errorf("expected %d sign up responses, got %d", numGuests, len(updatedEvent.SignUpResponses))
+	}
+}
+
+func TestConcurrentEventEdits(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	// Every edit and every responded remindee updates the event, and each update has to succeed within the maximum number of attempts
+	numEdits := 2
+	remindees := make([]models.Remindee, maxEventUpdateAttempts-numEdits)
+	for i := range remindees {
+		remindees[i] = models.Remindee{Email: fmt.Sprintf("remindee%d@example.com", i), Responded: utils.FalsePtr()}
+	}
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	event := insertTestEvent(t, models.Event{
+		Name:      "Edit concurrency test",
+		Type:      models.SPECIFIC_DATES,
+		Dates:     []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		Remindees: &remindees,
+	})
+
+	path := fmt.Sprintf("/api/events/%s", event.Id.Hex())
+	emails := utils.Map(remindees, func(r models.Remindee) string { return r.Email })
+	statuses := runInParallel(numEdits+len(remindees), func(i int) int {
+		if i < numEdits {
+			edit := gin.H{"name": fmt.Sprintf("Edit %d", i), "duration": 1, "dates": event.Dates, "type": event.Type, "remindees": emails}
+			return sendRequest(router, http.MethodPut, path, edit, "").Code
+		}
+		return sendRequest(router, http.MethodPost, path+"/responded", gin.H{"email": emails[i-numEdits]}, "").Code
+	})
+	for _, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("expected every request to succeed, got status %d", status)
+		}
+	}
+
+	// Edits made at the same time as remindees respond must not reset whether they responded
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	for _, remindee := range utils.Coalesce(updatedEvent.Remindees) {
+		if !utils.Coalesce(remindee.Responded) {
+			t.Errorf("expected %s to have responded", remindee.Email)
+		}
+	}
+	if len(utils.Coalesce(updatedEvent.Remindees)) != len(remindees) {
+		t.Errorf("expected the edits to keep all %d remindees, got %d", len(remindees), len(utils.Coalesce(updatedEvent.Remindees)))
+	}
+	if updatedEvent.Name != "Edit 0" && updatedEvent.Name != "Edit 1" {
+		t.Errorf("expected the event to be renamed by one of the edits, got %q", updatedEvent.Name)
+	}
+	if updatedEvent.Version != numEdits+len(remindees) {
+		t.Errorf("expected the event to be updated once per request, got version %d", updatedEvent.Version)
+	}
+}
+
+func TestConcurrentGroupDeclines(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	// Half of the attendees decline their invite and the other half delete their response, which also declines it
+	users := make([]*models.User, maxEventUpdateAttempts-1)
+	attendees := make([]models.Attendee, len(users))
+	responses := make([]models.EventResponse, len(users))
+	for i := range users {
+		users[i] = insertTestUser(t, fmt.Sprintf("attendee%d@example.com", i))
+		attendees[i] = models.Attendee{Email: users[i].Email, Declined: utils.FalsePtr()}
+		responses[i] = models.EventResponse{UserId: users[i].Id.Hex(), Response: &models.Response{}}
+	}
+	event := insertTestEvent(t, models.Event{
+		Name:      "Group concurrency test",
+		Type:      models.GROUP,
+		Attendees: &attendees,
+	})
+	if err := db.InsertEventResponses(event.Id, responses); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := runInParallel(len(users), func(i int) int {
+		userRouter := signedInAs(router, users[i])
+		if i%2 == 0 {
+			return sendRequest(userRouter, http.MethodPost, fmt.Sprintf("/api/events/%s/decline", event.Id.Hex()), nil, "").Code
+		}
+		body := gin.H{"guest": false, "userId": users[i].Id.Hex()}
+		return sendRequest(userRouter, http.MethodDelete, fmt.Sprintf("/api/events/%s/response", event.Id.Hex()), body, "").Code
+	})
+	for _, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("expected every request to succeed, got status %d", status)
+		}
+	}
+
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	for _, attendee := range utils.Coalesce(updatedEvent.Attendees) {
+		if !utils.Coalesce(attendee.Declined) {
+			t.Errorf("expected %s to have declined", attendee.Email)
+		}
+	}
+	if len(updatedEvent.ResponsesList) != len(users)/2 {
+		t.Errorf("expected only the responses of the attendees that declined their invite to be kept, got %d", len(updatedEvent.ResponsesList))
+	}
+
+	// Every decline is recorded in the history
+	removed := 0
+	for _, entry := range db.GetEventHistory(event.Id) {
+		if entry.Action == models.AttendeeRemovedAction {
+			removed++
+		}
+	}
+	if removed != len(users) {
+		t.Errorf("expected %d attendee removals in the history, got %d", len(users), removed)
+	}
+}
+
+func TestOwnerRemovesGroupMember(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	owner := insertTestUser(t, "owner@example.com")
+	member := insertTestUser(t, "member@example.com")
+	event := insertTestEvent(t, models.Event{
+		Name:      "Group",
+		Type:      models.GROUP,
+		OwnerId:   owner.Id,
+		Attendees: &[]models.Attendee{{Email: owner.Email, Declined: utils.FalsePtr()}, {Email: member.Email, Declined: utils.FalsePtr()}},
+	})
+	if err := db.InsertEventResponses(event.Id, []models.EventResponse{{UserId: member.Id.Hex(), Response: &models.Response{}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := gin.H{"guest": false, "userId": member.Id.Hex()}
+	if w := sendRequest(signedInAs(router, owner), http.MethodDelete, fmt.Sprintf("/api/events/%s/response", event.Id.Hex()), body, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the owner deleting the member's response to succeed, got status %d", w.Code)
+	}
+
+	// The member leaves the group, not the owner who removed them
+	attendees := utils.Coalesce(db.GetEventById(event.Id.Hex()).Attendees)
+	if len(attendees) != 2 || utils.Coalesce(attendees[0].Declined) || !utils.Coalesce(attendees[1].Declined) {
+		t.Errorf("expected only the member to have declined, got %+v", attendees)
+	}
+
+	var removed []models.EventHistoryEntry
+	for _, entry := range db.GetEventHistory(event.Id) {
+		if entry.Action == models.AttendeeRemovedAction {
+			removed = append(removed, entry)
+		}
+	}
+	if len(removed) != 1 || removed[0].Target != member.Email || removed[0].ActorId != owner.Id {
+		t.Errorf("expected the history to record the owner removing the member, got %+v", removed)
+	}
+}
+
+func TestGuestEventEditToken(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	editToken := "secret"
+	event := insertTestEvent(t, models.Event{
+		Name:      "Guest event",
+		Type:      models.SPECIFIC_DATES,
+		Dates:     []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC))},
+		EditToken: &editToken,
+	})
+
+	path := fmt.Sprintf("/api/events/%s", event.Id.Hex())
+	edit := gin.H{"name": "Renamed", "duration": 1, "dates": event.Dates, "type": event.Type}
+	if w := sendRequest(router, http.MethodPut, path, edit, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected editing without the edit token to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(router, http.MethodPut, path, edit, "wrong"); w.Code != http.StatusForbidden {
+		t.Errorf("expected editing with the wrong edit token to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(router, http.MethodPut, path, edit, editToken); w.Code != http.StatusOK {
+		t.Errorf("expected editing with the edit token to succeed, got status %d", w.Code)
+	}
+	if updatedEvent := db.GetEventById(event.Id.Hex()); updatedEvent.Name != "Renamed" {
+		t.Errorf("expected the event to be renamed, got %q", updatedEvent.Name)
+	}
+
+	if w := sendRequest(router, http.MethodDelete, path, nil, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected deleting without the edit token to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(router, http.MethodDelete, path, nil, editToken); w.Code != http.StatusOK {
+		t.Errorf("expected deleting with the edit token to succeed, got status %d", w.Code)
+	}
+	if db.GetEventById(event.Id.Hex()) != nil {
+		t.Error("expected the event to be deleted")
+	}
+
+	if w := sendRequest(router, http.MethodPost, path+"/restore", nil, editToken); w.Code != http.StatusOK {
+		t.Errorf("expected restoring with the edit token to succeed, got status %d", w.Code)
+	}
+	if db.GetEventById(event.Id.Hex()) == nil {
+		t.Error("expected the event to be restored")
+	}
+}
+
+func TestTransferGuestEvent(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	editToken := "secret"
+	event := insertTestEvent(t, models.Event{
+		Name:      "Guest event",
+		Type:      models.SPECIFIC_DATES,
+		Dates:     []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC))},
+		EditToken: &editToken,
+	})
+	guest := insertTestUser(t, "guest@example.com")
+	newOwner := insertTestUser(t, "owner@example.com")
+
+	path := fmt.Sprintf("/api/events/%s", event.Id.Hex())
+	transfer := gin.H{"email": newOwner.Email}
+	if w := sendRequest(signedInAs(router, guest), http.MethodPost, path+"/transfer", transfer, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected transferring without the edit token to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(signedInAs(router, guest), http.MethodPost, path+"/transfer", transfer, editToken); w.Code != http.StatusOK {
+		t.Fatalf("expected transferring with the edit token to succeed, got status %d", w.Code)
+	}
+
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	if updatedEvent.OwnerId != newOwner.Id {
+		t.Errorf("expected the event to be owned by the new owner, got %s", updatedEvent.OwnerId.Hex())
+	}
+	if updatedEvent.EditToken != nil {
+		t.Error("expected the edit token to be removed")
+	}
+
+	// The edit token stops working once the event has an owner
+	edit := gin.H{"name": "Renamed", "duration": 1, "dates": event.Dates, "type": event.Type}
+	if w := sendRequest(router, http.MethodPut, path, edit, editToken); w.Code != http.StatusForbidden {
+		t.Errorf("expected editing with the old edit token to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(signedInAs(router, newOwner), http.MethodPut, path, edit, ""); w.Code != http.StatusOK {
+		t.Errorf("expected the new owner to be able to edit the event, got status %d", w.Code)
+	}
+}
+
+func TestTransferGroup(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	owner := insertTestUser(t, "owner@example.com")
+	collaborator := insertTestUser(t, "collaborator@example.com")
+	event := insertTestEvent(t, models.Event{
+		Name:          "Group",
+		Type:          models.GROUP,
+		OwnerId:       owner.Id,
+		Attendees:     &[]models.Attendee{{Email: owner.Email, Declined: utils.FalsePtr()}, {Email: "Collaborator@example.com", Declined: utils.TruePtr()}},
+		Collaborators: &[]models.Collaborator{{UserId: collaborator.Id, Role: models.EditorRole}},
+	})
+
+	path := fmt.Sprintf("/api/events/%s/transfer", event.Id.Hex())
+	transfer := gin.H{"email": collaborator.Email}
+	if w := sendRequest(signedInAs(router, collaborator), http.MethodPost, path, transfer, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected an editor transferring the group to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(signedInAs(router, owner), http.MethodPost, path, transfer, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the owner transferring the group to succeed, got status %d", w.Code)
+	}
+
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	if updatedEvent.OwnerId != collaborator.Id {
+		t.Errorf("expected the group to be owned by the collaborator, got %s", updatedEvent.OwnerId.Hex())
+	}
+	if len(utils.Coalesce(updatedEvent.Collaborators)) != 0 {
+		t.Errorf("expected the new owner to be removed from the collaborators, got %+v", updatedEvent.Collaborators)
+	}
+	attendees := utils.Coalesce(updatedEvent.Attendees)
+	if len(attendees) != 2 || utils.Coalesce(attendees[1].Declined) {
+		t.Errorf("expected the new owner to be an attendee that hasn't declined, got %+v", attendees)
+	}
+
+	// The previous owner isn't a collaborator, so they lose access
+	if w := sendRequest(signedInAs(router, owner), http.MethodPost, path, gin.H{"email": owner.Email}, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected the previous owner to lose access, got status %d", w.Code)
+	}
+}
+
+func TestDeleteDuplicatedEvent(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	owner := insertTestUser(t, "owner@example.com")
+	taskIds := []string{"initial-reminder", "second-reminder", "final-reminder"}
+	event := insertTestEvent(t, models.Event{
+		Name:      "Event with reminders",
+		Type:      models.SPECIFIC_DATES,
+		Dates:     []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC))},
+		OwnerId:   owner.Id,
+		Remindees: &[]models.Remindee{{Email: "remindee@example.com", TaskIds: taskIds, Responded: utils.FalsePtr()}},
+		ScheduledEvent: &models.CalendarEvent{
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 16, 0, 0, 0, time.UTC)),
+		},
+		CalendarEventId:    "calendar-event",
+		CalendarAccountKey: owner.Email,
+		CalendarId:         "primary",
+		CalendarUserId:     owner.Id,
+		ScheduleSequence:   1,
+	})
+
+	ownerRouter := signedInAs(router, owner)
+	w := sendRequest(ownerRouter, http.MethodPost, fmt.Sprintf("/api/events/%s/duplicate", event.Id.Hex()), gin.H{"eventName": "Copy", "copyAvailability": false}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected duplicating the event to succeed, got status %d", w.Code)
+	}
+	var duplicate struct {
+		EventId string `json:"eventId"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &duplicate); err != nil {
+		t.Fatal(err)
+	}
+
+	// The duplicate isn't scheduled, so changing its scheduled event doesn't touch the calendar event of the original
+	copied := db.GetEventById(duplicate.EventId)
+	if copied.ScheduledEvent != nil || copied.CalendarEventId != "" || copied.CalendarId != "" || copied.ScheduleSequence != 0 {
+		t.Errorf("expected the duplicate not to be scheduled, got %+v", copied)
+	}
+
+	// The duplicate has no reminder tasks of its own, so deleting it doesn't cancel any
+	for _, remindee := range utils.Coalesce(copied.Remindees) {
+		if len(remindee.TaskIds) != 0 {
+			t.Fatalf("expected the duplicate not to share the reminder tasks, got %v", remindee.TaskIds)
+		}
+	}
+	if w := sendRequest(ownerRouter, http.MethodDelete, fmt.Sprintf("/api/events/%s", duplicate.EventId), nil, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected deleting the duplicate to succeed, got status %d", w.Code)
+	}
+
+	remindees := utils.Coalesce(db.GetEventById(event.Id.Hex()).Remindees)
+	if len(remindees) != 1 || !reflect.DeepEqual(remindees[0].TaskIds, taskIds) {
+		t.Errorf("expected the reminder tasks of the original event to be kept, got %+v", remindees)
+	}
+}
+
+func TestScheduledEventCalendarHidden(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	owner := insertTestUser(t, "owner@example.com")
+	event := insertTestEvent(t, models.Event{
+		Name:    "Scheduled event",
+		Type:    models.SPECIFIC_DATES,
+		Dates:   []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC))},
+		OwnerId: owner.Id,
+		ScheduledEvent: &models.CalendarEvent{
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 16, 0, 0, 0, time.UTC)),
+		},
+		CalendarEventId:    "calendar-event",
+		CalendarAccountKey: "calendar-account@example.com",
+		CalendarId:         "owner-calendar",
+		CalendarUserId:     owner.Id,
+	})
+
+	// Anyone with the link can view the event, so the calendar of the owner it was scheduled in isn't returned
+	w := sendRequest(router, http.MethodGet, fmt.Sprintf("/api/events/%s", event.Id.Hex()), nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected getting the event to succeed, got status %d", w.Code)
+	}
+	if body := w.Body.String(); strings.Contains(body, "calendar-account@example.com") || strings.Contains(body, "owner-calendar") {
+		t.Errorf("expected the calendar of the scheduled event to be hidden, got %s", body)
+	}
+}
+
+func TestClosedEvent(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+	t.Setenv("TASKS_SECRET", "tasks-secret")
+
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	closesAt := primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute))
+	event := insertTestEvent(t, models.Event{
+		Name:     "Closed event",
+		Type:     models.SPECIFIC_DATES,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		ClosesAt: &closesAt,
+	})
+
+	response := gin.H{"guest": true, "name": "Late guest", "availability": []primitive.DateTime{primitive.NewDateTimeFromTime(start)}}
+	if w := sendRequest(router, http.MethodPost, fmt.Sprintf("/api/events/%s/response", event.Id.Hex()), response, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected responding to a closed event to be forbidden, got status %d", w.Code)
+	}
+
+	// Only the first close task for the deadline closes the event
+	closeTask := gin.H{"eventId": event.Id.Hex(), "closesAt": closesAt}
+	for i := 0; i < 2; i++ {
+		if w := sendRequest(withHeader(router, "X-Tasks-Secret", "tasks-secret"), http.MethodPost, "/api/tasks/close-event", closeTask, ""); w.Code != http.StatusOK {
+			t.Errorf("expected the close task to succeed, got status %d", w.Code)
+		}
+	}
+
+	updatedEvent := db.GetEventById(event.Id.Hex())
+	if !updatedEvent.Closed || updatedEvent.Version != 1 {
+		t.Errorf("expected the event to be closed once, got closed %v and version %d", updatedEvent.Closed, updatedEvent.Version)
+	}
+}
+
+func TestLockedResponses(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	event := insertTestEvent(t, models.Event{
+		Name:                "Locked event",
+		Type:                models.SPECIFIC_DATES,
+		Dates:               []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		ResponsesLocked:     utils.TruePtr(),
+		UnlockedRespondents: &[]string{"Unlocked guest"},
+	})
+
+	path := fmt.Sprintf("/api/events/%s/response", event.Id.Hex())
+	availability := []primitive.DateTime{primitive.NewDateTimeFromTime(start)}
+	if w := sendRequest(router, http.MethodPost, path, gin.H{"guest": true, "name": "Locked guest", "availability": availability}, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected responding while responses are locked to be forbidden, got status %d", w.Code)
+	}
+	if w := sendRequest(router, http.MethodPost, path, gin.H{"guest": true, "name": "Unlocked guest", "availability": availability}, ""); w.Code != http.StatusOK {
+		t.Errorf("expected an unlocked respondent to be able to respond, got status %d", w.Code)
+	}
+
+	if w := sendRequest(router, http.MethodDelete, path, gin.H{"guest": true, "name": "Unlocked guest"}, ""); w.Code != http.StatusOK {
+		t.Errorf("expected an unlocked respondent to be able to delete their response, got status %d", w.Code)
+	}
+}
+
+func TestEventHistory(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	editToken := "secret"
+	duration := float32(1)
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	event := insertTestEvent(t, models.Event{
+		Name:      "Event with history",
+		Type:      models.SPECIFIC_DATES,
+		Duration:  &duration,
+		Dates:     []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		EditToken: &editToken,
+	})
+
+	path := fmt.Sprintf("/api/events/%s", event.Id.Hex())
+	availability := []primitive.DateTime{primitive.NewDateTimeFromTime(start)}
+	if w := sendRequest(router, http.MethodPost, path+"/response", gin.H{"guest": true, "name": "Guest", "availability": availability}, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected responding to succeed, got status %d", w.Code)
+	}
+	edit := gin.H{"name": "Renamed", "duration": 1, "dates": event.Dates, "type": event.Type}
+	if w := sendRequest(router, http.MethodPut, path, edit, editToken); w.Code != http.StatusOK {
+		t.Fatalf("expected editing to succeed, got status %d", w.Code)
+	}
+
+	if w := sendRequest(router, http.MethodGet, path+"/history", nil, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected viewing the history without the edit token to be forbidden, got status %d", w.Code)
+	}
+	w := sendRequest(router, http.MethodGet, path+"/history", nil, editToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected viewing the history with the edit token to succeed, got status %d", w.Code)
+	}
+
+	var history []models.EventHistoryEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(history))
+	}
+	if history[0].Action != models.EventEditedAction || len(history[0].Fields) != 1 || history[0].Fields[0] != "name" {
+		t.Errorf("expected the newest entry to be the edit of the name, got %+v", history[0])
+	}
+	if history[1].Action != models.ResponseAddedAction || history[1].ActorName != "Guest" || history[1].Target != "Guest" {
+		t.Errorf("expected the oldest entry to be the response of the guest, got %+v", history[1])
+	}
+}
+
+func TestEventComments(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	duration := float32(2)
+	start := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC)
+	event := insertTestEvent(t, models.Event{
+		Name:     "Event with comments",
+		Type:     models.SPECIFIC_DATES,
+		Duration: &duration,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+	})
+
+	path := fmt.Sprintf("/api/events/%s/comments", event.Id.Hex())
+	comment := gin.H{"name": "Guest", "text": "I can do 3pm but need to leave at 3:45", "slotStart": primitive.NewDateTimeFromTime(start)}
+	if w := sendRequest(router, http.MethodPost, path, comment, ""); w.Code != http.StatusCreated {
+		t.Errorf("expected commenting on a slot to succeed, got status %d", w.Code)
+	}
+	for i, invalidComment := range []gin.H{
+		{"name": "Guest", "text": "Outside of the event", "slotStart": primitive.NewDateTimeFromTime(start.Add(2 * time.Hour))},
+		{"name": "Guest", "text": " "},
+		{"text": "Without a name"},
+	} {
+		if w := sendRequest(router, http.MethodPost, path, invalidComment, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("expected invalid comment %d to be rejected, got status %d", i, w.Code)
+		}
+	}
+
+	comments := db.GetEventComments(event.Id)
+	if len(comments) != 1 || comments[0].Name != "Guest" || comments[0].SlotStart == nil {
+		t.Fatalf("expected the comment on the slot to be saved, got %+v", comments)
+	}
+
+	deletePath := fmt.Sprintf("%s/%s", path, comments[0].Id.Hex())
+	if w := sendRequest(router, http.MethodDelete, deletePath, nil, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected deleting someone else's comment to be forbidden, got status %d", w.Code)
+	}
+}
+
+func TestCustomQuestions(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	editToken := "secret"
+	sizeId := primitive.NewObjectID()
+	blockId := primitive.NewObjectID()
+	event := insertTestEvent(t, models.Event{
+		Name:            "Workshop",
+		Type:            models.SPECIFIC_DATES,
+		Dates:           []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC))},
+		EditToken:       &editToken,
+		IsSignUpForm:    utils.TruePtr(),
+		SignUpBlocks:    &[]models.SignUpBlock{{Id: blockId, Name: "Morning session"}},
+		SignUpResponses: make(map[string]*models.SignUpResponse),
+		Questions: &[]models.Question{
+			{Id: sizeId, Type: models.SingleChoiceQuestion, Prompt: "T-shirt size", Options: []string{"S", "M", "L"}, Required: true},
+		},
+	})
+
+	path := fmt.Sprintf("/api/events/%s/response", event.Id.Hex())
+	answered := gin.H{"guest": true, "name": "Guest", "signUpBlockIds": []primitive.ObjectID{blockId}, "answers": gin.H{sizeId.Hex(): []string{"M"}}}
+	if w := sendRequest(router, http.MethodPost, path, answered, ""); w.Code != http.StatusOK {
+		t.Errorf("expected answering the required question to succeed, got status %d", w.Code)
+	}
+	unanswered := gin.H{"guest": true, "name": "Unanswered", "signUpBlockIds": []primitive.ObjectID{blockId}}
+	if w := sendRequest(router, http.MethodPost, path, unanswered, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected leaving out a required answer to be rejected, got status %d", w.Code)
+	}
+
+	w := sendRequest(router, http.MethodGet, fmt.Sprintf("/api/events/%s/export", event.Id.Hex()), nil, editToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected exporting with the edit token to succeed, got status %d", w.Code)
+	}
+	expected := "Name,Email,Sign up blocks,T-shirt size\nGuest,,Morning session,M\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected export %q, got %q", expected, w.Body.String())
+	}
+}
+
+func TestPolls(t *testing.T) {
+	initTestDb(t)
+	router := newTestRouter()
+
+	editToken := "secret"
+	event := insertTestEvent(t, models.Event{
+		Name:                     "Event with a poll",
+		Type:                     models.SPECIFIC_DATES,
+		Dates:                    []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC))},
+		EditToken:                &editToken,
+		BlindAvailabilityEnabled: utils.TruePtr(),
+	})
+
+	path := fmt.Sprintf("/api/events/%s/polls", event.Id.Hex())
+	newPoll := gin.H{"question": "Where shall we meet?", "type": models.RankedPoll, "options": []string{"Park", "Cafe"}}
+	if w := sendRequest(router, http.MethodPost, path, newPoll, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected adding a poll without the edit token to be forbidden, got status %d", w.Code)
+	}
+	w := sendRequest(router, http.MethodPost, path, newPoll, editToken)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected adding a poll with the edit token to succeed, got status %d", w.Code)
+	}
+	var poll models.Poll
+	if err := json.Unmarshal(w.Body.Bytes(), &poll); err != nil {
+		t.Fatal(err)
+	}
+	park, cafe := poll.Options[0].Id, poll.Options[1].Id
+
+	votePath := fmt.Sprintf("%s/%s/vote", path, poll.Id.Hex())
+	statuses := sendInParallel(router, http.MethodPost, votePath, []interface{}{
+		gin.H{"guest": true, "name": "a", "optionIds": []primitive.ObjectID{cafe, park}},
+		gin.H{"guest": true, "name": "b", "optionIds": []primitive.ObjectID{cafe}},
+		gin.H{"guest": true, "name": "c", "optionIds": []primitive.ObjectID{park, park}},
+	})
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusOK {
+		t.Errorf("expected valid votes to succeed, got statuses %v", statuses)
+	}
+	if statuses[2] != http.StatusBadRequest {
+		t.Errorf("expected ranking an option twice to be rejected, got status %d", statuses[2])
+	}
+
+	voter := insertTestUser(t, "voter@example.com")
+	voterRouter := signedInAs(router, voter)
+	if w := sendRequest(voterRouter, http.MethodPost, votePath, gin.H{"guest": false, "optionIds": []primitive.ObjectID{park}}, ""); w.Code != http.StatusOK {
+		t.Errorf("expected a signed in user's vote to succeed, got status %d", w.Code)
+	}
+
+	var result []pollWithResults
+	if err := json.Unmarshal(sendRequest(router, http.MethodGet, path+"?name=a", nil, "").Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Results != nil || result[0].Vote != nil {
+		t.Errorf("expected blind availability to hide the results and the votes of guests, got %+v", result)
+	}
+	if err := json.Unmarshal(sendRequest(voterRouter, http.MethodGet, path, nil, "").Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Results != nil || result[0].Vote == nil {
+		t.Errorf("expected blind availability to hide the results but not the signed in user's own vote, got %+v", result)
+	}
+
+	// Deleting a response deletes the votes along with it
+	responsePath := fmt.Sprintf("/api/events/%s/response", event.Id.Hex())
+	if w := sendRequest(voterRouter, http.MethodDelete, responsePath, gin.H{"guest": false, "userId": voter.Id.Hex()}, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected deleting the signed in user's response to succeed, got status %d", w.Code)
+	}
+
+	if err := json.Unmarshal(sendRequest(router, http.MethodGet, path, nil, editToken).Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Results == nil || result[0].Results.TotalVotes != 2 {
+		t.Fatalf("expected the owner to see the results of both guest votes, got %+v", result)
+	}
+	if scores := []int{result[0].Results.Options[0].Score, result[0].Results.Options[1].Score}; scores[0] != 1 || scores[1] != 4 {
+		t.Errorf("expected Borda scores [1 4], got %v", scores)
+	}
+
+	if w := sendRequest(router, http.MethodDelete, responsePath, gin.H{"guest": true, "name": "a"}, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected deleting the guest's response to succeed, got status %d", w.Code)
+	}
+	if votes := db.GetPollVotes(event.Id); len(votes) != 1 || votes[0].UserId != "b" {
+		t.Errorf("expected only the vote of b to be left, got %+v", votes)
+	}
+}
+
+func TestGetParticipantEmails(t *testing.T) {
+	event := &models.Event{
+		ResponsesList: []models.EventResponse{
+			{UserId: "Guest", Response: &models.Response{Name: "Guest", Email: "guest@example.com"}},
+			{UserId: "Other guest", Response: &models.Response{Name: "Other guest", Email: "Remindee@example.com"}},
+		},
+		Remindees: &[]models.Remindee{{Email: "remindee@example.com"}, {Email: " "}},
+		Attendees: &[]models.Attendee{
+			{Email: "attendee@example.com", Declined: utils.FalsePtr()},
+			{Email: "declined@example.com", Declined: utils.TruePtr()},
+		},
+	}
+
+	emails := getParticipantEmails(event)
+	expected := []string{"guest@example.com", "Remindee@example.com", "attendee@example.com"}
+	if len(emails) != len(expected) {
+		t.Fatalf("expected emails %v, got %v", expected, emails)
+	}
+	for i := range expected {
+		if emails[i] != expected[i] {
+			t.Errorf("expected emails %v, got %v", expected, emails)
+			break
+		}
+	}
+}
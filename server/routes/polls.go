@@ -0,0 +1,296 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/eventstream"
+	"schej.it/server/services/polls"
+	"schej.it/server/utils"
+)
+
+// A poll along with its results and the vote of the respondent asking for it
+type pollWithResults struct {
+	models.Poll
+
+	// Nil for people who can't see the results because blind availability is enabled
+	Results *polls.Results `json:"results"`
+
+	// Nil if the respondent hasn't voted
+	Vote *models.PollVote `json:"vote"`
+}
+
+// @Summary Gets the polls of an event with their results
+// @Description When blind availability is enabled, only owners and viewers see the results. Signed in users also get their own votes
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []pollWithResults
+// @Router /events/{eventId}/polls [get]
+func getPolls(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	// Guests are only identified by their name, so their votes aren't returned since anyone could ask for them
+	userId := getSessionUserId(c)
+	showResults := !utils.Coalesce(event.BlindAvailabilityEnabled) || event.HasRole(userId, models.ViewerRole) || hasEditToken(c, event)
+
+	votes := db.GetPollVotes(event.Id)
+	result := make([]pollWithResults, 0)
+	for _, poll := range utils.Coalesce(event.Polls) {
+		item := pollWithResults{Poll: poll}
+		if showResults {
+			results := polls.GetResults(&poll, votes)
+			item.Results = &results
+		}
+		if userId != primitive.NilObjectID {
+			if i := utils.Find(votes, func(vote models.PollVote) bool { return vote.PollId == poll.Id && vote.UserId == userId.Hex() }); i != -1 {
+				item.Vote = &votes[i]
+			}
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Attaches a poll to an event
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{question=string,type=models.PollType,options=[]string} true "Object containing the question, how to vote on it, and the text of each option"
+// @Success 201 {object} models.Poll
+// @Router /events/{eventId}/polls [post]
+func addPoll(c *gin.Context) {
+	payload := struct {
+		Question string          `json:"question" binding:"required"`
+		Type     models.PollType `json:"type" binding:"required"`
+		Options  []string        `json:"options" binding:"required"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	poll := models.Poll{
+		Id:       primitive.NewObjectID(),
+		Question: strings.TrimSpace(payload.Question),
+		Type:     payload.Type,
+		Options: utils.Map(payload.Options, func(text string) models.PollOption {
+			return models.PollOption{Id: primitive.NewObjectID(), Text: strings.TrimSpace(text)}
+		}),
+	}
+	if !poll.IsValid() {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidPoll})
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	_, err := saveEventUpdate(event, func(event *models.Event) bool {
+		eventPolls := append(utils.Coalesce(event.Polls), poll)
+		event.Polls = &eventPolls
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.PollsUpdated})
+	recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.PollAddedAction, Target: poll.Id.Hex()})
+
+	c.JSON(http.StatusCreated, poll)
+}
+
+// @Summary Removes a poll from an event along with its votes
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param pollId path string true "Poll ID"
+// @Success 200
+// @Router /events/{eventId}/polls/{pollId} [delete]
+func deletePoll(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !authorizeEventRole(c, event, models.EditorRole) {
+		return
+	}
+
+	poll := event.GetPoll(c.Param("pollId"))
+	if poll == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.PollNotFound})
+		return
+	}
+	pollId := poll.Id
+
+	deleted, err := saveEventUpdate(event, func(event *models.Event) bool {
+		eventPolls := utils.Coalesce(event.Polls)
+		i := utils.Find(eventPolls, func(poll models.Poll) bool { return poll.Id == pollId })
+		if i == -1 {
+			return false
+		}
+		eventPolls = append(eventPolls[:i], eventPolls[i+1:]...)
+		event.Polls = &eventPolls
+		return true
+	})
+	if errors.Is(err, db.ErrEventVersionConflict) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventUpdateConflict})
+		return
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if deleted {
+		if err := db.DeletePollVotes(event.Id, pollId); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+
+		eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.PollsUpdated})
+		recordEventHistory(c, event, "", models.EventHistoryEntry{Action: models.PollDeletedAction, Target: pollId.Hex()})
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Votes on a poll of an event, replacing the previous vote of the respondent
+// @Description For ranked polls, the options are in order from most to least preferred, and don't all have to be ranked
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param pollId path string true "Poll ID"
+// @Param payload body object{guest=bool,name=string,optionIds=[]string} true "Object containing who is voting and the chosen options"
+// @Success 200
+// @Router /events/{eventId}/polls/{pollId}/vote [post]
+func votePoll(c *gin.Context) {
+	payload := struct {
+		Guest     *bool                `json:"guest" binding:"required"`
+		Name      string               `json:"name"`
+		OptionIds []primitive.ObjectID `json:"optionIds" binding:"required"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	event, poll, vote := getPollVoter(c, *payload.Guest, payload.Name)
+	if vote == nil {
+		return
+	}
+	if !poll.IsValidVote(payload.OptionIds) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidVote})
+		return
+	}
+
+	vote.OptionIds = payload.OptionIds
+	if err := db.UpsertPollVote(vote); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.PollsUpdated})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Removes the vote of the respondent on a poll of an event
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param pollId path string true "Poll ID"
+// @Param payload body object{guest=bool,name=string} true "Object containing who is removing their vote"
+// @Success 200
+// @Router /events/{eventId}/polls/{pollId}/vote [delete]
+func deletePollVote(c *gin.Context) {
+	payload := struct {
+		Guest *bool  `json:"guest" binding:"required"`
+		Name  string `json:"name"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	event, _, vote := getPollVoter(c, *payload.Guest, payload.Name)
+	if vote == nil {
+		return
+	}
+
+	if err := db.DeletePollVote(event.Id, vote.PollId, vote.UserId); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.PollsUpdated})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// Returns the event and poll of the request, and a vote identifying the respondent like their response to the event.
+// Responds with an error and returns a nil vote if the poll doesn't exist, the respondent is unknown, or they can't change their vote
+func getPollVoter(c *gin.Context, guest bool, name string) (*models.Event, *models.Poll, *models.PollVote) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return nil, nil, nil
+	}
+	poll := event.GetPoll(c.Param("pollId"))
+	if poll == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.PollNotFound})
+		return nil, nil, nil
+	}
+
+	vote := &models.PollVote{EventId: event.Id, PollId: poll.Id}
+	if guest {
+		vote.UserId = strings.TrimSpace(name)
+		vote.Name = vote.UserId
+		if len(vote.UserId) == 0 {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidVote})
+			return nil, nil, nil
+		}
+	} else {
+		userId := getSessionUserId(c)
+		if userId == primitive.NilObjectID {
+			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
+			return nil, nil, nil
+		}
+		vote.UserId = userId.Hex()
+		if user := db.GetUserById(vote.UserId); user != nil {
+			vote.Name = strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+		}
+	}
+
+	// Votes are part of the response, so they are closed and locked along with it
+	if event.IsClosed() {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.EventClosed})
+		return nil, nil, nil
+	}
+	if event.IsResponseLocked(vote.UserId) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.ResponsesLocked})
+		return nil, nil, nil
+	}
+
+	return event, poll, vote
+}
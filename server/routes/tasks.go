@@ -0,0 +1,123 @@
+/* The /tasks group contains the routes that are called by Cloud Tasks */
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/availability"
+	"schej.it/server/services/eventstream"
+	"schej.it/server/services/listmonk"
+	"schej.it/server/utils"
+)
+
+func InitTasks(router *gin.RouterGroup) {
+	tasksRouter := router.Group("/tasks")
+	tasksRouter.Use(middleware.TasksAuthRequired())
+
+	tasksRouter.POST("/close-event", closeEvent)
+	tasksRouter.POST("/purge-deleted-events", purgeDeletedEvents)
+}
+
+// @Summary Closes an event once its response deadline has passed
+// @Description Cancels the reminder emails and sends the owner a summary with the best times. Does nothing if the deadline has changed since the task was created, or if the event was already closed
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param payload body object{eventId=string,closesAt=string} true "Object containing the event and the deadline the task was created for"
+// @Success 200
+// @Router /tasks/close-event [post]
+func closeEvent(c *gin.Context) {
+	payload := struct {
+		EventId  string             `json:"eventId" binding:"required"`
+		ClosesAt primitive.DateTime `json:"closesAt" binding:"required"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	eventId, err := primitive.ObjectIDFromHex(payload.EventId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	closed, err := db.CloseEvent(eventId, payload.ClosesAt)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if !closed {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	event := db.GetEventById(payload.EventId)
+	if event == nil {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	eventstream.Publish(event.Id.Hex(), eventstream.Message{Type: eventstream.EventClosed})
+
+	cancelReminders(event)
+
+	// Send the owner a summary of the responses
+	owner := db.GetUserById(event.OwnerId.Hex())
+	if owner == nil {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	topSlots := make([]bson.M, 0)
+	if !utils.Coalesce(event.IsSignUpForm) {
+		for _, slot := range availability.GetBestTimes(event, availability.BestTimesOptions{Length: time.Hour, Limit: 3}) {
+			topSlots = append(topSlots, bson.M{
+				"startDate":    slot.StartDate.Time().Format(time.RFC3339),
+				"endDate":      slot.EndDate.Time().Format(time.RFC3339),
+				"numAvailable": len(slot.Available),
+				"numIfNeeded":  len(slot.IfNeeded),
+			})
+		}
+	}
+
+	numResponses := len(event.ResponsesList)
+	if utils.Coalesce(event.IsSignUpForm) {
+		numResponses = len(event.SignUpResponses)
+	}
+
+	eventClosedEmailId := 17
+	listmonk.SendEmail(owner.Email, eventClosedEmailId, bson.M{
+		"eventName":    event.Name,
+		"ownerName":    owner.FirstName,
+		"eventUrl":     fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()),
+		"numResponses": numResponses,
+		"topSlots":     topSlots,
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Permanently deletes the events that have been in the trash for longer than the retention period
+// @Description Called daily by Cloud Scheduler
+// @Tags tasks
+// @Produce json
+// @Success 200 {object} object{numPurged=int}
+// @Router /tasks/purge-deleted-events [post]
+func purgeDeletedEvents(c *gin.Context) {
+	numPurged, err := db.PurgeDeletedEvents(time.Now().Add(-models.EventRetentionPeriod))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"numPurged": numPurged})
+}
@@ -3,14 +3,17 @@ package routes
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"schej.it/server/db"
 	"schej.it/server/errs"
 	"schej.it/server/logger"
@@ -36,10 +39,14 @@ func InitUser(router *gin.RouterGroup) {
 	userRouter.POST("/add-google-calendar-account", addGoogleCalendarAccount)
 	userRouter.POST("/add-apple-calendar-account", addAppleCalendarAccount)
 	userRouter.POST("/add-outlook-calendar-account", addOutlookCalendarAccount)
+	userRouter.POST("/add-ics-calendar-account", addICSCalendarAccount)
+	userRouter.POST("/add-caldav-calendar-account", addCalDAVCalendarAccount)
 	userRouter.DELETE("/remove-calendar-account", removeCalendarAccount)
 	userRouter.POST("/toggle-calendar", toggleCalendar)
 	userRouter.POST("/toggle-sub-calendar", toggleSubCalendar)
 	userRouter.GET("/searchContacts", searchContacts)
+	userRouter.POST("/calendar-feed", createCalendarFeed)
+	userRouter.DELETE("/calendar-feed", deleteCalendarFeed)
 	userRouter.DELETE("", deleteUser)
 }
 
@@ -138,41 +145,30 @@ func updateCalendarOptions(c *gin.Context) {
 }
 
 // @Summary Gets all the user's events
-// @Description Returns an array containing all the user's events
+// @Description Returns an array containing all the user's events, and the events in their trash that they can still restore
 // @Tags user
 // @Produce json
-// @Success 200 {object} object{events=[]models.Event,joinedEvents=[]models.Event}
+// @Success 200 {object} object{events=[]models.Event,joinedEvents=[]models.Event,deletedEvents=[]models.Event}
 // @Router /user/events [get]
 func getEvents(c *gin.Context) {
 	user := utils.GetAuthUser(c)
 	userId := user.Id
 
 	// Get the events associated with the current user
-	events := make([]models.Event, 0)
-	opts := options.Find().SetSort(bson.M{"_id": -1})
-
-	cursor, err := db.EventsCollection.Find(context.Background(), bson.M{
-		"$or": bson.A{
-			bson.M{"ownerId": userId},
-			bson.M{"responses.userId": userId.Hex()},
-			bson.M{"attendees": bson.M{"email": user.Email, "declined": false}},
-		},
-	}, opts)
-	if err != nil {
-		logger.StdErr.Panicln(err)
-	}
-	if err := cursor.All(context.Background(), &events); err != nil {
-		logger.StdErr.Panicln(err)
-	}
+	events := db.GetEventsForUser(user)
 
 	response := make(map[string][]models.Event)
-	response["events"] = make([]models.Event, 0)       // The events the user created
+	response["events"] = make([]models.Event, 0)       // The events the user created or is an editor of
 	response["joinedEvents"] = make([]models.Event, 0) // The events the user has responded to
+	response["deletedEvents"] = db.GetDeletedEventsForUser(user)
 
 	// Convert events to old format for backward compatibility
 	for i := range events {
 		utils.ConvertEventToOldFormat(&events[i])
 	}
+	for i := range response["deletedEvents"] {
+		utils.ConvertEventToOldFormat(&response["deletedEvents"][i])
+	}
 
 	for _, event := range events {
 		// Get rid of responses so we don't send too much data when fetching all events
@@ -180,8 +176,8 @@ func getEvents(c *gin.Context) {
 			event.ResponsesMap[id] = nil
 		}
 
-		// Filter into events user created and responded to
-		if event.OwnerId == userId {
+		// Filter into events user manages and responded to
+		if event.HasRole(userId, models.EditorRole) {
 			response["events"] = append(response["events"], event)
 		} else {
 			response["joinedEvents"] = append(response["joinedEvents"], event)
@@ -311,7 +307,10 @@ func addAppleCalendarAccount(c *gin.Context) {
 		AppleCalendarAuth: *auth,
 	}
 	_, err = calendarProvider.GetCalendarList()
-	if err != nil {
+	if errors.Is(err, calendar.ErrInternalAddress) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidCalendarUrl})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.InvalidCredentials})
 		return
 	}
@@ -376,11 +375,114 @@ func addOutlookCalendarAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{})
 }
 
+// @Summary Adds a calendar account on an arbitrary CalDAV server (e.g. Nextcloud, Radicale, Fastmail)
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{serverUrl=string,username=string,password=string} true "Object containing the base url of the CalDAV server and the username and app password of the account"
+// @Success 200
+// @Router /user/add-caldav-calendar-account [post]
+func addCalDAVCalendarAccount(c *gin.Context) {
+	payload := struct {
+		ServerUrl string `json:"serverUrl" binding:"required"`
+		Username  string `json:"username" binding:"required"`
+		Password  string `json:"password" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	serverUrl, err := url.Parse(payload.ServerUrl)
+	if err != nil || (serverUrl.Scheme != "https" && serverUrl.Scheme != "http") || len(serverUrl.Host) == 0 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidCalendarUrl})
+		return
+	}
+
+	encryptedPassword, err := utils.Encrypt(payload.Password)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	auth := &models.CalDAVCalendarAuth{
+		ServerUrl: serverUrl.String(),
+		Username:  payload.Username,
+		Password:  encryptedPassword,
+	}
+
+	// Check if the provided credentials are valid
+	calendarProvider := calendar.CalDAVCalendar{
+		CalDAVCalendarAuth: *auth,
+	}
+	_, err = calendarProvider.GetCalendarList()
+	if errors.Is(err, calendar.ErrInternalAddress) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidCalendarUrl})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.InvalidCredentials})
+		return
+	}
+
+	// Usernames aren't always emails, so include the server to tell apart accounts with the same username on different servers
+	email := payload.Username
+	if !strings.Contains(email, "@") {
+		email = fmt.Sprintf("%s@%s", payload.Username, serverUrl.Host)
+	}
+
+	addCalendarAccount(c, addCalendarAccountArgs{
+		calendarType:       models.CalDAVCalendarType,
+		calDAVCalendarAuth: auth,
+		email:              email,
+		picture:            "",
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Adds a calendar that is only available as an .ics url
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{url=string,name=string} true "Object containing the public or secret .ics url of the calendar and the name to display it with"
+// @Success 200
+// @Router /user/add-ics-calendar-account [post]
+func addICSCalendarAccount(c *gin.Context) {
+	payload := struct {
+		Url  string `json:"url" binding:"required"`
+		Name string `json:"name" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	// Check if the provided url is a valid calendar
+	if _, err := calendar.FetchICSCalendar(payload.Url); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidCalendarUrl})
+		return
+	}
+
+	encryptedUrl, err := utils.Encrypt(payload.Url)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	// ICS calendars aren't associated with an email, so the name is used to identify the calendar account instead
+	addCalendarAccount(c, addCalendarAccountArgs{
+		calendarType:    models.ICSCalendarType,
+		icsCalendarAuth: &models.ICSCalendarAuth{Url: encryptedUrl},
+		email:           payload.Name,
+		picture:         "",
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
 // Implements the shared functionality for adding a calendar account
 type addCalendarAccountArgs struct {
 	calendarType       models.CalendarType
 	oAuth2CalendarAuth *models.OAuth2CalendarAuth
 	appleCalendarAuth  *models.AppleCalendarAuth
+	icsCalendarAuth    *models.ICSCalendarAuth
+	calDAVCalendarAuth *models.CalDAVCalendarAuth
 	email              string
 	picture            string
 }
@@ -404,6 +506,10 @@ func addCalendarAccount(c *gin.Context, args addCalendarAccountArgs) {
 		calendarAccount.OAuth2CalendarAuth = args.oAuth2CalendarAuth
 	case models.AppleCalendarType:
 		calendarAccount.AppleCalendarAuth = args.appleCalendarAuth
+	case models.ICSCalendarType:
+		calendarAccount.ICSCalendarAuth = args.icsCalendarAuth
+	case models.CalDAVCalendarType:
+		calendarAccount.CalDAVCalendarAuth = args.calDAVCalendarAuth
 	}
 	calendarAccountKey := utils.GetCalendarAccountKey(args.email, args.calendarType)
 
@@ -571,6 +677,52 @@ func searchContacts(c *gin.Context) {
 	c.JSON(http.StatusOK, contacts)
 }
 
+// @Summary Creates a new calendar subscription feed url for the user
+// @Description Generates a new secret token for the user's calendar feed, invalidating any previous feed url
+// @Tags user
+// @Produce json
+// @Success 200 {object} object{url=string,webcalUrl=string}
+// @Router /user/calendar-feed [post]
+func createCalendarFeed(c *gin.Context) {
+	authUser := utils.GetAuthUser(c)
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	_, err = db.UsersCollection.UpdateByID(context.Background(), authUser.Id, bson.M{
+		"$set": bson.M{"calendarFeedToken": token},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	feedUrl := getCalendarFeedUrl(token)
+	c.JSON(http.StatusOK, gin.H{
+		"url":       feedUrl,
+		"webcalUrl": "webcal://" + strings.TrimPrefix(strings.TrimPrefix(feedUrl, "https://"), "http://"),
+	})
+}
+
+// @Summary Disables the user's calendar subscription feed
+// @Tags user
+// @Produce json
+// @Success 200
+// @Router /user/calendar-feed [delete]
+func deleteCalendarFeed(c *gin.Context) {
+	authUser := utils.GetAuthUser(c)
+
+	_, err := db.UsersCollection.UpdateByID(context.Background(), authUser.Id, bson.M{
+		"$unset": bson.M{"calendarFeedToken": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
 // @Summary Deletes the currently signed in user
 // @Tags user
 // @Produce json
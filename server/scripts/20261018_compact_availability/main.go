@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// Event contains only the fields needed for the migration. models.SlotSet decodes the old array of timestamps
+// and encodes it as intervals, so decoding and writing back the responses is enough to convert them
+type Event struct {
+	Id            primitive.ObjectID     `bson:"_id,omitempty"`
+	ResponsesList []models.EventResponse `bson:"responses"`
+}
+
+func main() {
+	// Initialize database connection
+	disconnect := db.Init()
+	defer disconnect()
+
+	batchSize := int32(1000)
+	totalUpdated := 0
+	lastId := primitive.NilObjectID
+
+	for {
+		// Get events that still have availability stored as an array of timestamps in batches
+		filter := bson.M{
+			"$or": bson.A{
+				bson.M{"responses.response.availability": bson.M{"$type": "date"}},
+				bson.M{"responses.response.ifNeeded": bson.M{"$type": "date"}},
+			},
+		}
+		if lastId != primitive.NilObjectID {
+			filter["_id"] = bson.M{"$gt": lastId}
+		}
+
+		cursor, err := db.EventsCollection.Find(
+			context.Background(),
+			filter,
+			options.Find().
+				SetBatchSize(batchSize).
+				SetLimit(int64(batchSize)).
+				SetSort(bson.D{{Key: "_id", Value: 1}}).
+				SetProjection(bson.M{"_id": 1, "responses": 1}),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		count := cursor.RemainingBatchLength()
+
+		var operations []mongo.WriteModel
+		for cursor.Next(context.Background()) {
+			var event Event
+			if err := cursor.Decode(&event); err != nil {
+				fmt.Printf("Warning: Failed to decode event, skipping: %v\n", err)
+				lastId = event.Id
+				continue
+			}
+
+			lastId = event.Id
+
+			// Create update operation
+			update := mongo.NewUpdateOneModel()
+			update.SetFilter(bson.M{"_id": event.Id})
+			update.SetUpdate(bson.M{
+				"$set": bson.M{
+					"responses": event.ResponsesList,
+				},
+			})
+			operations = append(operations, update)
+		}
+
+		if err := cursor.Err(); err != nil {
+			fmt.Printf("Warning: Cursor error: %v\n", err)
+		}
+		cursor.Close(context.Background())
+
+		// Execute batch update
+		if len(operations) > 0 {
+			result, err := db.EventsCollection.BulkWrite(context.Background(), operations)
+			if err != nil {
+				log.Fatal(err)
+			}
+			totalUpdated += int(result.ModifiedCount)
+			fmt.Printf("Updated %d events in batch, total updated: %d\n", result.ModifiedCount, totalUpdated)
+		}
+
+		// Check if we've processed all documents
+		if count < int(batchSize) {
+			break
+		}
+	}
+
+	fmt.Printf("Migration complete. Updated %d events total\n", totalUpdated)
+
+	os.Exit(0)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"schej.it/server/db"
+)
+
+func main() {
+	// Initialize database connection
+	disconnect := db.Init()
+	defer disconnect()
+
+	// Create index for getting the history of an event from newest to oldest
+	_, err := db.EventHistoryCollection.Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "eventId", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().
+				SetName("eventId_1__id_-1"),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Created index on eventId and _id")
+}
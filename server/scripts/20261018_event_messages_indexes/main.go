@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"schej.it/server/db"
+)
+
+func main() {
+	// Initialize database connection
+	disconnect := db.Init()
+	defer disconnect()
+
+	// Live update messages are delivered through change streams as soon as they are inserted, so they only need to be kept briefly
+	_, err := db.EventMessagesCollection.Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "createdAt", Value: 1},
+			},
+			Options: options.Index().
+				SetName("createdAt_1").
+				SetExpireAfterSeconds(60 * 60),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Created TTL index on createdAt")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// OldEvent represents the event structure before the migration, with responses stored in the event document
+type OldEvent struct {
+	Id            primitive.ObjectID     `bson:"_id,omitempty"`
+	ResponsesList []models.EventResponse `bson:"responses"`
+}
+
+func main() {
+	// Initialize database connection
+	disconnect := db.Init()
+	defer disconnect()
+
+	// Create indexes for looking up the responses to an event and the events a user responded to
+	_, err := db.EventResponsesCollection.Indexes().CreateMany(
+		context.Background(),
+		[]mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "eventId", Value: 1},
+					{Key: "userId", Value: 1},
+				},
+				Options: options.Index().
+					SetName("eventId_userId_1").
+					SetUnique(true),
+			},
+			{
+				Keys: bson.D{
+					{Key: "userId", Value: 1},
+					{Key: "eventId", Value: 1},
+				},
+				Options: options.Index().
+					SetName("userId_eventId_1"),
+			},
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Created indexes on eventId and userId")
+
+	batchSize := int32(1000)
+	totalEvents := 0
+	totalResponses := 0
+	lastId := primitive.NilObjectID
+
+	for {
+		// Get events that still have responses in the event document in batches
+		filter := bson.M{"responses": bson.M{"$exists": true}}
+		if lastId != primitive.NilObjectID {
+			filter["_id"] = bson.M{"$gt": lastId}
+		}
+
+		cursor, err := db.EventsCollection.Find(
+			context.Background(),
+			filter,
+			options.Find().
+				SetBatchSize(batchSize).
+				SetLimit(int64(batchSize)).
+				SetSort(bson.D{{Key: "_id", Value: 1}}).
+				SetProjection(bson.M{"_id": 1, "responses": 1}),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		count := cursor.RemainingBatchLength()
+
+		var responseOperations []mongo.WriteModel
+		var eventOperations []mongo.WriteModel
+		for cursor.Next(context.Background()) {
+			var oldEvent OldEvent
+			if err := cursor.Decode(&oldEvent); err != nil {
+				fmt.Printf("Warning: Failed to decode event, skipping: %v\n", err)
+				lastId = oldEvent.Id
+				continue
+			}
+
+			lastId = oldEvent.Id
+
+			// Upsert so that the migration can safely be run again if it is interrupted
+			for _, eventResponse := range oldEvent.ResponsesList {
+				update := mongo.NewUpdateOneModel()
+				update.SetFilter(bson.M{"eventId": oldEvent.Id, "userId": eventResponse.UserId})
+				update.SetUpdate(bson.M{"$set": bson.M{"response": eventResponse.Response}})
+				update.SetUpsert(true)
+				responseOperations = append(responseOperations, update)
+			}
+
+			// Remove responses from the event document
+			update := mongo.NewUpdateOneModel()
+			update.SetFilter(bson.M{"_id": oldEvent.Id})
+			update.SetUpdate(bson.M{
+				"$unset": bson.M{
+					"responses":    "",
+					"responsesMap": "",
+				},
+			})
+			eventOperations = append(eventOperations, update)
+		}
+
+		if err := cursor.Err(); err != nil {
+			fmt.Printf("Warning: Cursor error: %v\n", err)
+		}
+		cursor.Close(context.Background())
+
+		// Responses must be written before they are removed from the events
+		if len(responseOperations) > 0 {
+			if _, err := db.EventResponsesCollection.BulkWrite(context.Background(), responseOperations); err != nil {
+				log.Fatal(err)
+			}
+			totalResponses += len(responseOperations)
+		}
+		if len(eventOperations) > 0 {
+			result, err := db.EventsCollection.BulkWrite(context.Background(), eventOperations)
+			if err != nil {
+				log.Fatal(err)
+			}
+			totalEvents += int(result.ModifiedCount)
+			fmt.Printf("Migrated %d events in batch, total events: %d, total responses: %d\n", result.ModifiedCount, totalEvents, totalResponses)
+		}
+
+		// Check if we've processed all documents
+		if count < int(batchSize) {
+			break
+		}
+	}
+
+	fmt.Printf("Migration complete. Moved %d responses out of %d events\n", totalResponses, totalEvents)
+
+	// The index on responses in the events collection is no longer used
+	_, err = db.EventsCollection.Indexes().DropOne(
+		context.Background(),
+		"responses_userId_id_1",
+	)
+	if err != nil {
+		fmt.Printf("Warning: Failed to drop old responses index: %v\n", err)
+	}
+
+	os.Exit(0)
+}
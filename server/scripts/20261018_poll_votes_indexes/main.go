@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"schej.it/server/db"
+)
+
+func main() {
+	// Initialize database connection
+	disconnect := db.Init()
+	defer disconnect()
+
+	// Create index for looking up the votes on the polls of an event, which also keeps each respondent to one vote per poll
+	_, err := db.PollVotesCollection.Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "eventId", Value: 1},
+				{Key: "pollId", Value: 1},
+				{Key: "userId", Value: 1},
+			},
+			Options: options.Index().
+				SetName("eventId_pollId_userId_1").
+				SetUnique(true),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Created index on eventId, pollId, and userId")
+}
@@ -0,0 +1,183 @@
+package availability
+
+import (
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// The granularity of the availability grid
+const SlotDuration = models.SlotDuration
+
+// A candidate meeting time along with who can attend it
+type TimeSlot struct {
+	StartDate primitive.DateTime `json:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate"`
+
+	// Ids (keys of the responses map) of the respondents in each category
+	Available []string `json:"available"`
+	IfNeeded  []string `json:"ifNeeded"`
+	Missing   []string `json:"missing"`
+}
+
+type BestTimesOptions struct {
+	// Length of the meeting to find a time for. Ignored for days only events
+	Length time.Duration
+
+	// Maximum number of slots to return, 0 returns all candidate slots
+	Limit int
+}
+
+// Returns the candidate slots for the given event ranked from best to worst.
+// A respondent is available for a slot if every 15 minute increment of the slot is in their availability,
+// if needed if every increment is in either their availability or their if needed times, and missing otherwise.
+// Days are in the event's time zone, so slots of days only events span a whole local day even across DST transitions
+func GetBestTimes(event *models.Event, options BestTimesOptions) []TimeSlot {
+	daysOnly := utils.Coalesce(event.DaysOnly)
+	if !daysOnly && options.Length <= 0 {
+		return make([]TimeSlot, 0)
+	}
+
+	loc := utils.GetLocation(event.TimeZone)
+	respondents := getRespondents(event)
+	slots := make([]TimeSlot, 0)
+	for _, start := range getCandidateStartTimes(event, options.Length, loc) {
+		slots = append(slots, evaluateSlot(respondents, start, getSlotEnd(event, start, options.Length, loc), daysOnly))
+	}
+
+	// Rank by number of people available, then by number of people available if needed, then chronologically
+	sort.SliceStable(slots, func(i, j int) bool {
+		if len(slots[i].Available) != len(slots[j].Available) {
+			return len(slots[i].Available) > len(slots[j].Available)
+		}
+		if len(slots[i].IfNeeded) != len(slots[j].IfNeeded) {
+			return len(slots[i].IfNeeded) > len(slots[j].IfNeeded)
+		}
+		return slots[i].StartDate < slots[j].StartDate
+	})
+
+	if options.Limit > 0 && len(slots) > options.Limit {
+		slots = slots[:options.Limit]
+	}
+
+	return slots
+}
+
+// Returns who is available, available if needed, and missing for the slot starting at the given time
+func GetTimeSlot(event *models.Event, start time.Time, length time.Duration) TimeSlot {
+	end := getSlotEnd(event, start, length, utils.GetLocation(event.TimeZone))
+	return evaluateSlot(getRespondents(event), start, end, utils.Coalesce(event.DaysOnly))
+}
+
+// Returns the end of a slot of the given length, or the end of the local day for days only events
+func getSlotEnd(event *models.Event, start time.Time, length time.Duration, loc *time.Location) time.Time {
+	if utils.Coalesce(event.DaysOnly) {
+		return utils.AddDays(start, 1, loc)
+	}
+	return start.Add(length)
+}
+
+// A respondent's availability indexed for constant time lookups
+type respondent struct {
+	id           string
+	availability models.Set[int64]
+	ifNeeded     models.Set[int64]
+	timeZone     *string
+}
+
+func getRespondents(event *models.Event) []respondent {
+	respondents := make([]respondent, 0)
+	for _, eventResponse := range event.ResponsesList {
+		if eventResponse.Response == nil {
+			continue
+		}
+		respondents = append(respondents, respondent{
+			id:           eventResponse.UserId,
+			availability: toSet(eventResponse.Response.Availability),
+			ifNeeded:     toSet(eventResponse.Response.IfNeeded),
+			timeZone:     eventResponse.Response.TimeZone,
+		})
+	}
+	return respondents
+}
+
+func evaluateSlot(respondents []respondent, start time.Time, end time.Time, daysOnly bool) TimeSlot {
+	slot := TimeSlot{
+		StartDate: primitive.NewDateTimeFromTime(start),
+		EndDate:   primitive.NewDateTimeFromTime(end),
+		Available: make([]string, 0),
+		IfNeeded:  make([]string, 0),
+		Missing:   make([]string, 0),
+	}
+
+	// Days only events only have a single timestamp for each day
+	increments := []time.Time{start}
+	if !daysOnly {
+		increments = make([]time.Time, 0)
+		for t := start; t.Before(end); t = t.Add(SlotDuration) {
+			increments = append(increments, t)
+		}
+	}
+
+	for _, r := range respondents {
+		available, ifNeeded := true, true
+		for _, t := range increments {
+			_, inAvailability := r.availability[t.UnixMilli()]
+			_, inIfNeeded := r.ifNeeded[t.UnixMilli()]
+			if !inAvailability {
+				available = false
+			}
+			if !inAvailability && !inIfNeeded {
+				ifNeeded = false
+				break
+			}
+		}
+
+		if available {
+			slot.Available = append(slot.Available, r.id)
+		} else if ifNeeded {
+			slot.IfNeeded = append(slot.IfNeeded, r.id)
+		} else {
+			slot.Missing = append(slot.Missing, r.id)
+		}
+	}
+
+	return slot
+}
+
+// Returns every time a meeting of the given length could start, i.e. every 15 minute increment
+// such that the meeting still ends within the time range of that day. The time range ends at the same wall clock
+// time in loc on every day, so it is an hour shorter or longer on days with a DST transition
+func getCandidateStartTimes(event *models.Event, length time.Duration, loc *time.Location) []time.Time {
+	dates := make([]time.Time, 0)
+	for _, date := range event.Dates {
+		dates = append(dates, date.Time().UTC())
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	if utils.Coalesce(event.DaysOnly) {
+		return dates
+	}
+
+	dayLength := time.Duration(float64(utils.Coalesce(event.Duration)) * float64(time.Hour))
+	startTimes := make([]time.Time, 0)
+	for _, date := range dates {
+		dayEnd := utils.AddWallClock(date, dayLength, loc)
+		for t := date; !t.Add(length).After(dayEnd); t = t.Add(SlotDuration) {
+			startTimes = append(startTimes, t)
+		}
+	}
+
+	return startTimes
+}
+
+func toSet(timestamps []primitive.DateTime) models.Set[int64] {
+	set := make(models.Set[int64])
+	for _, timestamp := range timestamps {
+		set[int64(timestamp)] = struct{}{}
+	}
+	return set
+}
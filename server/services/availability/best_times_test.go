@@ -0,0 +1,193 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func dateTimes(start time.Time, n int) []primitive.DateTime {
+	times := make([]primitive.DateTime, 0)
+	for i := 0; i < n; i++ {
+		times = append(times, primitive.NewDateTimeFromTime(start.Add(time.Duration(i)*SlotDuration)))
+	}
+	return times
+}
+
+func TestGetBestTimes(t *testing.T) {
+	day := time.Date(2024, 5, 6, 16, 0, 0, 0, time.UTC)
+	duration := float32(2)
+
+	event := &models.Event{
+		Duration: &duration,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(day)},
+		ResponsesList: []models.EventResponse{
+			// Available 16:00 - 17:00
+			{UserId: "a", Response: &models.Response{Availability: dateTimes(day, 4)}},
+			// Available 16:30 - 18:00
+			{UserId: "b", Response: &models.Response{Availability: dateTimes(day.Add(30*time.Minute), 6)}},
+			// Available 16:00 - 16:30, if needed 16:30 - 17:00
+			{UserId: "c", Response: &models.Response{
+				Availability: dateTimes(day, 2),
+				IfNeeded:     dateTimes(day.Add(30*time.Minute), 2),
+			}},
+		},
+	}
+
+	slots := GetBestTimes(event, BestTimesOptions{Length: time.Hour})
+
+	// 16:00, 16:15, ..., 17:00 are the only start times that fit a 1 hour meeting in a 2 hour day
+	if len(slots) != 5 {
+		t.Fatalf("expected 5 slots, got %d", len(slots))
+	}
+
+	best := slots[0]
+	if !best.StartDate.Time().Equal(day) {
+		t.Errorf("expected best slot to start at %v, got %v", day, best.StartDate.Time())
+	}
+	if len(best.Available) != 1 || best.Available[0] != "a" {
+		t.Errorf("expected only a to be available, got %v", best.Available)
+	}
+	if len(best.IfNeeded) != 1 || best.IfNeeded[0] != "c" {
+		t.Errorf("expected only c to be available if needed, got %v", best.IfNeeded)
+	}
+	if len(best.Missing) != 1 || best.Missing[0] != "b" {
+		t.Errorf("expected only b to be missing, got %v", best.Missing)
+	}
+
+	limited := GetBestTimes(event, BestTimesOptions{Length: time.Hour, Limit: 2})
+	if len(limited) != 2 {
+		t.Errorf("expected limit to be applied, got %d slots", len(limited))
+	}
+}
+
+func TestGetBestTimesDaysOnly(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(24 * time.Hour)
+
+	event := &models.Event{
+		DaysOnly: utils.TruePtr(),
+		Dates: []primitive.DateTime{
+			primitive.NewDateTimeFromTime(monday),
+			primitive.NewDateTimeFromTime(tuesday),
+		},
+		ResponsesList: []models.EventResponse{
+			{UserId: "a", Response: &models.Response{Availability: []primitive.DateTime{primitive.NewDateTimeFromTime(tuesday)}}},
+			{UserId: "b", Response: &models.Response{IfNeeded: []primitive.DateTime{primitive.NewDateTimeFromTime(tuesday)}}},
+		},
+	}
+
+	slots := GetBestTimes(event, BestTimesOptions{})
+	if len(slots) != 2 {
+		t.Fatalf("expected 2 slots, got %d", len(slots))
+	}
+	if !slots[0].StartDate.Time().Equal(tuesday) {
+		t.Errorf("expected tuesday to be the best day, got %v", slots[0].StartDate.Time())
+	}
+	if slots[0].EndDate.Time().Sub(slots[0].StartDate.Time()) != 24*time.Hour {
+		t.Errorf("expected day slots to be 24 hours long")
+	}
+}
+
+func TestGetTimeSlot(t *testing.T) {
+	day := time.Date(2024, 5, 6, 16, 0, 0, 0, time.UTC)
+	duration := float32(2)
+
+	event := &models.Event{
+		Duration: &duration,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(day)},
+		ResponsesList: []models.EventResponse{
+			// Available 16:00 - 17:00
+			{UserId: "a", Response: &models.Response{Availability: dateTimes(day, 4)}},
+			// Available 16:00 - 16:30, if needed 16:30 - 17:00
+			{UserId: "b", Response: &models.Response{
+				Availability: dateTimes(day, 2),
+				IfNeeded:     dateTimes(day.Add(30*time.Minute), 2),
+			}},
+			// Available 16:00 - 16:45
+			{UserId: "c", Response: &models.Response{Availability: dateTimes(day, 3)}},
+			// Hasn't filled out their availability
+			{UserId: "d"},
+		},
+	}
+
+	// The chosen slot doesn't have to be one of the candidate slots, e.g. a 45 minute meeting starting at 16:15
+	slot := GetTimeSlot(event, day.Add(15*time.Minute), 45*time.Minute)
+	if !slot.StartDate.Time().Equal(day.Add(15*time.Minute)) || !slot.EndDate.Time().Equal(day.Add(time.Hour)) {
+		t.Errorf("expected slot from 16:15 to 17:00, got %v to %v", slot.StartDate.Time(), slot.EndDate.Time())
+	}
+	if len(slot.Available) != 1 || slot.Available[0] != "a" {
+		t.Errorf("expected only a to be available, got %v", slot.Available)
+	}
+	if len(slot.IfNeeded) != 1 || slot.IfNeeded[0] != "b" {
+		t.Errorf("expected only b to be available if needed, got %v", slot.IfNeeded)
+	}
+	if len(slot.Missing) != 1 || slot.Missing[0] != "c" {
+		t.Errorf("expected only c to be missing, got %v", slot.Missing)
+	}
+}
+
+func TestGetTimeSlotDaysOnly(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	event := &models.Event{
+		DaysOnly: utils.TruePtr(),
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(monday)},
+		ResponsesList: []models.EventResponse{
+			{UserId: "a", Response: &models.Response{Availability: []primitive.DateTime{primitive.NewDateTimeFromTime(monday)}}},
+		},
+	}
+
+	// The length is ignored for days only events, since the whole day is scheduled
+	slot := GetTimeSlot(event, monday, time.Hour)
+	if slot.EndDate.Time().Sub(slot.StartDate.Time()) != 24*time.Hour {
+		t.Errorf("expected the slot to be 24 hours long, got %v", slot.EndDate.Time().Sub(slot.StartDate.Time()))
+	}
+	if len(slot.Available) != 1 || slot.Available[0] != "a" {
+		t.Errorf("expected a to be available, got %v", slot.Available)
+	}
+}
+
+func TestGetBestTimesAcrossDST(t *testing.T) {
+	timeZone := "America/New_York"
+	loc, _ := time.LoadLocation(timeZone)
+	duration := float32(17)
+
+	// Clocks go back at 2am on November 3, 2024, so midnight to 5pm is 18 hours long that day
+	day := time.Date(2024, 11, 3, 0, 0, 0, 0, loc)
+	event := &models.Event{
+		Duration: &duration,
+		TimeZone: &timeZone,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(day)},
+	}
+
+	// Nobody responded, so the slots are in chronological order and the last 8 hour meeting starts at 9am
+	slots := GetBestTimes(event, BestTimesOptions{Length: 8 * time.Hour})
+	if expected := (18-8)*4 + 1; len(slots) != expected {
+		t.Fatalf("expected %d slots, got %d", expected, len(slots))
+	}
+	if expected := time.Date(2024, 11, 3, 9, 0, 0, 0, loc); !slots[len(slots)-1].StartDate.Time().Equal(expected) {
+		t.Errorf("expected the last slot to start at %v, got %v", expected, slots[len(slots)-1].StartDate.Time().In(loc))
+	}
+}
+
+func TestGetTimeSlotDaysOnlyAcrossDST(t *testing.T) {
+	timeZone := "America/New_York"
+	loc, _ := time.LoadLocation(timeZone)
+
+	// Clocks go forward on March 10, 2024, so the day is 23 hours long
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, loc)
+	event := &models.Event{
+		DaysOnly: utils.TruePtr(),
+		TimeZone: &timeZone,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(day)},
+	}
+
+	slot := GetTimeSlot(event, day, 0)
+	if expected := time.Date(2024, 3, 11, 0, 0, 0, 0, loc); !slot.EndDate.Time().Equal(expected) {
+		t.Errorf("expected the slot to end at %v, got %v", expected, slot.EndDate.Time().In(loc))
+	}
+}
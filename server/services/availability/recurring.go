@@ -0,0 +1,194 @@
+package availability
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+const week = 7 * 24 * time.Hour
+
+// A weekly slot of a recurring meeting poll, along with how it holds up over the whole recurrence
+type RecurringSlot struct {
+	TimeSlot
+
+	Occurrences []Occurrence `json:"occurrences"`
+
+	// Lowest number of available respondents without a calendar conflict in any single week
+	MinAvailable int `json:"minAvailable"`
+
+	// Number of occurrences where at least one respondent who can attend has a calendar conflict
+	NumConflictingWeeks int `json:"numConflictingWeeks"`
+}
+
+// A single meeting of a recurring slot
+type Occurrence struct {
+	StartDate primitive.DateTime `json:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate"`
+
+	// Ids of the available (or available if needed) respondents that have a calendar event during this occurrence
+	Conflicts []string `json:"conflicts"`
+
+	// Ids of the respondents that are available on the DOW grid, but whose time zone puts this occurrence
+	// outside of their availability (e.g. the week between two countries' DST transitions)
+	Unavailable []string `json:"unavailable"`
+}
+
+// Returns the start of every occurrence within the recurrence of a weekly meeting starting at the given time on the DOW grid.
+// Occurrences keep the same wall clock time in loc, so they don't shift by an hour after a DST transition
+func GetOccurrences(recurrence models.Recurrence, start time.Time, loc *time.Location) []time.Time {
+	interval := recurrence.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	rangeStart, rangeEnd := recurrence.StartDate.Time(), recurrence.EndDate.Time()
+
+	// Number of weeks between the DOW grid and the first occurrence on or after the start of the range.
+	// The estimate can be off by one week when the range starts within an hour of an occurrence, so correct it
+	firstWeek := int(math.Ceil(float64(rangeStart.Sub(start)) / float64(week)))
+	if !utils.AddWeeks(start, firstWeek-1, loc).Before(rangeStart) {
+		firstWeek--
+	} else if utils.AddWeeks(start, firstWeek, loc).Before(rangeStart) {
+		firstWeek++
+	}
+
+	occurrences := make([]time.Time, 0)
+	for i := firstWeek; ; i += interval {
+		occurrence := utils.AddWeeks(start, i, loc)
+		if !occurrence.Before(rangeEnd) {
+			break
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences
+}
+
+// Returns the candidate slots of a recurring meeting poll ranked by how many respondents can attend every week.
+// busyTimes maps respondent ids to their calendar events. Respondents without calendar events are assumed to be free every week
+func GetRecurringSlots(event *models.Event, busyTimes map[string][]models.CalendarEvent, options BestTimesOptions) []RecurringSlot {
+	recurrence := utils.Coalesce(event.Recurrence)
+	loc := utils.GetLocation(event.TimeZone)
+	respondents := getRespondentsInOtherTimeZones(event)
+
+	recurringSlots := make([]RecurringSlot, 0)
+	for _, slot := range GetBestTimes(event, BestTimesOptions{Length: options.Length}) {
+		recurringSlot := RecurringSlot{
+			TimeSlot:     slot,
+			Occurrences:  make([]Occurrence, 0),
+			MinAvailable: len(slot.Available),
+		}
+		length := slot.EndDate.Time().Sub(slot.StartDate.Time())
+
+		for _, start := range GetOccurrences(recurrence, slot.StartDate.Time(), loc) {
+			occurrence := Occurrence{
+				StartDate:   primitive.NewDateTimeFromTime(start),
+				EndDate:     primitive.NewDateTimeFromTime(start.Add(length)),
+				Conflicts:   make([]string, 0),
+				Unavailable: make([]string, 0),
+			}
+
+			// Respondents whose clocks change on a different date than the event's see this occurrence at a different
+			// local time than on the DOW grid, so check their availability at the time they'd actually be meeting
+			unavailable := make(models.Set[string])
+			weeks := int(math.Round(float64(start.Sub(slot.StartDate.Time())) / float64(week)))
+			for _, r := range respondents {
+				localStart := utils.AddWeeks(start, -weeks, r.loc)
+				if localStart.Equal(slot.StartDate.Time()) {
+					continue
+				}
+				if len(evaluateSlot([]respondent{r.respondent}, localStart, localStart.Add(length), false).Available) == 0 {
+					unavailable[r.id] = struct{}{}
+				}
+			}
+
+			numAvailable := len(slot.Available)
+			for _, id := range slot.Available {
+				if _, ok := unavailable[id]; ok {
+					occurrence.Unavailable = append(occurrence.Unavailable, id)
+					numAvailable--
+				} else if isBusy(busyTimes[id], start, start.Add(length)) {
+					occurrence.Conflicts = append(occurrence.Conflicts, id)
+					numAvailable--
+				}
+			}
+			for _, id := range slot.IfNeeded {
+				if isBusy(busyTimes[id], start, start.Add(length)) {
+					occurrence.Conflicts = append(occurrence.Conflicts, id)
+				}
+			}
+
+			if len(occurrence.Conflicts) > 0 {
+				recurringSlot.NumConflictingWeeks++
+			}
+			if numAvailable < recurringSlot.MinAvailable {
+				recurringSlot.MinAvailable = numAvailable
+			}
+
+			recurringSlot.Occurrences = append(recurringSlot.Occurrences, occurrence)
+		}
+
+		recurringSlots = append(recurringSlots, recurringSlot)
+	}
+
+	// Rank by the number of people available in the worst week, then by the number of weeks with conflicts,
+	// then by the number of people available if needed, then chronologically
+	sort.SliceStable(recurringSlots, func(i, j int) bool {
+		if recurringSlots[i].MinAvailable != recurringSlots[j].MinAvailable {
+			return recurringSlots[i].MinAvailable > recurringSlots[j].MinAvailable
+		}
+		if recurringSlots[i].NumConflictingWeeks != recurringSlots[j].NumConflictingWeeks {
+			return recurringSlots[i].NumConflictingWeeks < recurringSlots[j].NumConflictingWeeks
+		}
+		if len(recurringSlots[i].IfNeeded) != len(recurringSlots[j].IfNeeded) {
+			return len(recurringSlots[i].IfNeeded) > len(recurringSlots[j].IfNeeded)
+		}
+		return recurringSlots[i].StartDate < recurringSlots[j].StartDate
+	})
+
+	if options.Limit > 0 && len(recurringSlots) > options.Limit {
+		recurringSlots = recurringSlots[:options.Limit]
+	}
+
+	return recurringSlots
+}
+
+// A respondent that filled out their availability in a different time zone than the event was created in
+type localRespondent struct {
+	respondent
+	loc *time.Location
+}
+
+// Returns the respondents whose time zone differs from the event's. Only timed events where both time zones are known are considered
+func getRespondentsInOtherTimeZones(event *models.Event) []localRespondent {
+	respondents := make([]localRespondent, 0)
+	if event.TimeZone == nil || utils.Coalesce(event.DaysOnly) {
+		return respondents
+	}
+
+	for _, r := range getRespondents(event) {
+		if r.timeZone == nil || *r.timeZone == *event.TimeZone {
+			continue
+		}
+		respondents = append(respondents, localRespondent{respondent: r, loc: utils.GetLocation(r.timeZone)})
+	}
+	return respondents
+}
+
+// Returns whether any of the calendar events that the user isn't free during overlap with the given time range
+func isBusy(calendarEvents []models.CalendarEvent, start time.Time, end time.Time) bool {
+	for _, calendarEvent := range calendarEvents {
+		if calendarEvent.Free {
+			continue
+		}
+		if calendarEvent.StartDate.Time().Before(end) && calendarEvent.EndDate.Time().After(start) {
+			return true
+		}
+	}
+	return false
+}
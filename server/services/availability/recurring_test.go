@@ -0,0 +1,147 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetOccurrences(t *testing.T) {
+	// Tuesday 3pm on the DOW grid
+	start := time.Date(2018, 6, 19, 15, 0, 0, 0, time.UTC)
+	recurrence := models.Recurrence{
+		StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 9, 4, 0, 0, 0, 0, time.UTC)),  // Wednesday
+		EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)), // Wednesday
+	}
+
+	occurrences := GetOccurrences(recurrence, start, time.UTC)
+	if len(occurrences) != 4 {
+		t.Fatalf("expected 4 occurrences, got %d: %v", len(occurrences), occurrences)
+	}
+	if first := time.Date(2024, 9, 10, 15, 0, 0, 0, time.UTC); !occurrences[0].Equal(first) {
+		t.Errorf("expected first occurrence to be %v, got %v", first, occurrences[0])
+	}
+
+	recurrence.Interval = 2
+	if occurrences := GetOccurrences(recurrence, start, time.UTC); len(occurrences) != 2 {
+		t.Errorf("expected 2 occurrences every other week, got %d", len(occurrences))
+	}
+}
+
+func TestGetOccurrencesAcrossDST(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	// Tuesday 3pm in New York on the DOW grid, which is during DST
+	start := time.Date(2018, 6, 19, 15, 0, 0, 0, newYork)
+	recurrence := models.Recurrence{
+		StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 10, 28, 0, 0, 0, 0, newYork)),
+		EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 11, 13, 0, 0, 0, 0, newYork)),
+	}
+
+	occurrences := GetOccurrences(recurrence, start, newYork)
+	if len(occurrences) != 3 {
+		t.Fatalf("expected 3 occurrences, got %d: %v", len(occurrences), occurrences)
+	}
+	for _, occurrence := range occurrences {
+		if local := occurrence.In(newYork); local.Weekday() != time.Tuesday || local.Hour() != 15 {
+			t.Errorf("expected occurrence to be on Tuesday at 3pm in New York, got %v", local)
+		}
+	}
+}
+
+func TestGetRecurringSlotsInOtherTimeZone(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	newYorkName, londonName := "America/New_York", "Europe/London"
+
+	// 10am in New York is 3pm in London on the DOW grid
+	start := time.Date(2018, 6, 19, 10, 0, 0, 0, newYork)
+	duration := float32(1)
+
+	event := &models.Event{
+		Type:     models.DOW,
+		Duration: &duration,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		TimeZone: &newYorkName,
+		Recurrence: &models.Recurrence{
+			// London falls back a week before New York does
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 10, 20, 0, 0, 0, 0, newYork)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 11, 10, 0, 0, 0, 0, newYork)),
+		},
+		ResponsesList: []models.EventResponse{
+			{UserId: "a", Response: &models.Response{Availability: dateTimes(start, 4), TimeZone: &newYorkName}},
+			{UserId: "b", Response: &models.Response{Availability: dateTimes(start, 4), TimeZone: &londonName}},
+		},
+	}
+
+	slots := GetRecurringSlots(event, nil, BestTimesOptions{Length: time.Hour})
+	if len(slots) != 1 {
+		t.Fatalf("expected 1 slot, got %d", len(slots))
+	}
+
+	slot := slots[0]
+	if len(slot.Occurrences) != 3 || slot.MinAvailable != 1 {
+		t.Fatalf("expected 3 occurrences with one week missing a respondent, got %+v", slot)
+	}
+	for i, occurrence := range slot.Occurrences {
+		expected := 0
+		if i == 1 {
+			expected = 1
+		}
+		if len(occurrence.Unavailable) != expected {
+			t.Errorf("expected %d unavailable respondents in week %d, got %v", expected, i, occurrence.Unavailable)
+		}
+	}
+}
+
+func TestGetRecurringSlots(t *testing.T) {
+	tuesday := time.Date(2018, 6, 19, 15, 0, 0, 0, time.UTC)
+	wednesday := tuesday.Add(24 * time.Hour)
+	duration := float32(1)
+
+	event := &models.Event{
+		Type:     models.DOW,
+		Duration: &duration,
+		Dates:    []primitive.DateTime{primitive.NewDateTimeFromTime(tuesday), primitive.NewDateTimeFromTime(wednesday)},
+		Recurrence: &models.Recurrence{
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 9, 29, 0, 0, 0, 0, time.UTC)),
+		},
+		ResponsesList: []models.EventResponse{
+			{UserId: "a", Response: &models.Response{Availability: append(dateTimes(tuesday, 4), dateTimes(wednesday, 4)...)}},
+			{UserId: "b", Response: &models.Response{Availability: append(dateTimes(tuesday, 4), dateTimes(wednesday, 4)...)}},
+		},
+	}
+
+	// b is busy on Tuesday 3pm in one week, and has a free event on Wednesday
+	busyTimes := map[string][]models.CalendarEvent{
+		"b": {
+			{
+				StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 9, 17, 15, 30, 0, 0, time.UTC)),
+				EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 9, 17, 16, 30, 0, 0, time.UTC)),
+			},
+			{
+				StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 9, 18, 15, 0, 0, 0, time.UTC)),
+				EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 9, 18, 16, 0, 0, 0, time.UTC)),
+				Free:      true,
+			},
+		},
+	}
+
+	slots := GetRecurringSlots(event, busyTimes, BestTimesOptions{Length: time.Hour})
+	if len(slots) != 2 {
+		t.Fatalf("expected 2 slots, got %d", len(slots))
+	}
+
+	best, worst := slots[0], slots[1]
+	if !best.StartDate.Time().Equal(wednesday) || best.NumConflictingWeeks != 0 || best.MinAvailable != 2 {
+		t.Errorf("expected wednesday to have no conflicts, got %+v", best)
+	}
+	if !worst.StartDate.Time().Equal(tuesday) || worst.NumConflictingWeeks != 1 || worst.MinAvailable != 1 {
+		t.Errorf("expected tuesday to have one conflicting week, got %+v", worst)
+	}
+	if len(worst.Occurrences) != 4 || len(worst.Occurrences[2].Conflicts) != 1 || worst.Occurrences[2].Conflicts[0] != "b" {
+		t.Errorf("expected b to have a conflict in the third week, got %+v", worst.Occurrences)
+	}
+}
@@ -0,0 +1,275 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/google/uuid"
+	"github.com/jonyTF/go-webdav"
+	"github.com/jonyTF/go-webdav/caldav"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/ics"
+	"schej.it/server/utils"
+)
+
+// Http client for requests to CalDAV servers, which are given by users
+var caldavHttpClient = newExternalHttpClient(30 * time.Second)
+
+type CalDAVCalendar struct {
+	models.CalDAVCalendarAuth
+}
+
+func (calendar *CalDAVCalendar) GetCalendarList() (map[string]models.SubCalendar, error) {
+	webdavClient, caldavClient, err := calendar.getClients()
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := webdavClient.FindCurrentUserPrincipal(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	calendarHomeSet, err := caldavClient.FindCalendarHomeSet(context.Background(), principal)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := caldavClient.FindCalendars(context.Background(), calendarHomeSet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only include calendars that support VEVENT
+	filteredCalendars := make(map[string]models.SubCalendar)
+	for _, calendar := range calendars {
+		for _, supportedComponent := range calendar.SupportedComponentSet {
+			if supportedComponent == "VEVENT" {
+				filteredCalendars[calendar.Path] = models.SubCalendar{
+					Name:    calendar.Name,
+					Enabled: utils.TruePtr(),
+				}
+				break
+			}
+		}
+	}
+
+	return filteredCalendars, nil
+}
+
+func (calendar *CalDAVCalendar) GetCalendarEvents(calendarId string, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error) {
+	_, caldavClient, err := calendar.getClients()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get events
+	events, err := caldavClient.QueryCalendar(context.Background(), calendarId, &caldav.CalendarQuery{
+		CompRequest: caldav.CalendarCompRequest{
+			Name: "VCALENDAR",
+			Comps: []caldav.CalendarCompRequest{{
+				Name: "VEVENT",
+				Props: []string{
+					"SUMMARY",
+					"UID",
+					"DTSTART",
+					"DTEND",
+					"DURATION",
+				},
+			}},
+			Expand: &caldav.CalendarExpandRequest{
+				Start: timeMin,
+				End:   timeMax,
+			},
+		},
+		CompFilter: caldav.CompFilter{
+			Name: "VCALENDAR",
+			Comps: []caldav.CompFilter{{
+				Name:  "VEVENT",
+				Start: timeMin,
+				End:   timeMax,
+			}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredEvents []models.CalendarEvent
+	for _, event := range events {
+		if event.Data == nil || len(event.Data.Children) == 0 {
+			continue
+		}
+
+		calendarEvent, ok := getCalDAVCalendarEvent(event.Data.Children[0])
+		if !ok {
+			continue
+		}
+		calendarEvent.CalendarId = calendarId
+		filteredEvents = append(filteredEvents, calendarEvent)
+	}
+
+	return filteredEvents, nil
+}
+
+// Converts a VEVENT returned by a CalDAV server into a calendar event. Returns false for all day events and events
+// whose times can't be parsed. The end time comes from DTEND, or from DTSTART and DURATION if the event has no DTEND
+func getCalDAVCalendarEvent(component *ical.Component) (models.CalendarEvent, bool) {
+	startProp := component.Props.Get(ical.PropDateTimeStart)
+	// Filter out all day events
+	if startProp == nil || !strings.Contains(startProp.Value, "T") {
+		return models.CalendarEvent{}, false
+	}
+
+	// Get time objects from time string taking timezone into account
+	startTime, err := parseTimeWithTZ(startProp)
+	if err != nil {
+		return models.CalendarEvent{}, false
+	}
+
+	var endTime time.Time
+	if endProp := component.Props.Get(ical.PropDateTimeEnd); endProp != nil {
+		endTime, err = parseTimeWithTZ(endProp)
+		if err != nil {
+			return models.CalendarEvent{}, false
+		}
+	} else if durationProp := component.Props.Get(ical.PropDuration); durationProp != nil {
+		duration, err := durationProp.Duration()
+		if err != nil {
+			return models.CalendarEvent{}, false
+		}
+		endTime = startTime.Add(duration)
+	} else {
+		// Events with neither take up no time
+		endTime = startTime
+	}
+
+	calendarEvent := models.CalendarEvent{
+		StartDate: primitive.NewDateTimeFromTime(startTime),
+		EndDate:   primitive.NewDateTimeFromTime(endTime),
+	}
+	if uidProp := component.Props.Get(ical.PropUID); uidProp != nil {
+		calendarEvent.Id = uidProp.Value
+	}
+	if summaryProp := component.Props.Get(ical.PropSummary); summaryProp != nil {
+		calendarEvent.Summary = summaryProp.Value
+	}
+
+	return calendarEvent, true
+}
+
+func (calendar *CalDAVCalendar) getClients() (*webdav.Client, *caldav.Client, error) {
+	decryptedPassword, err := utils.Decrypt(calendar.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The server url is chosen by the user, so don't let it point at internal services
+	serverUrl, err := url.Parse(calendar.ServerUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkExternalUrl(serverUrl); err != nil {
+		return nil, nil, err
+	}
+
+	httpClient := webdav.HTTPClientWithBasicAuth(caldavHttpClient, calendar.Username, decryptedPassword)
+
+	webdavClient, err := webdav.NewClient(httpClient, calendar.ServerUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caldavClient, err := caldav.NewClient(httpClient, calendar.ServerUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return webdavClient, caldavClient, nil
+}
+
+func parseTimeWithTZ(prop *ical.Prop) (time.Time, error) {
+	timeStr := prop.Value
+	tzID := prop.Params.Get("TZID")
+
+	var t time.Time
+	var err error
+
+	if tzID != "" {
+		loc, err := time.LoadLocation(tzID)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone: %v", err)
+		}
+		//lint:ignore SA4006 err is in fact used later in the code
+		t, err = time.ParseInLocation("20060102T150405", timeStr, loc)
+	} else {
+		t, err = time.Parse("20060102T150405Z", timeStr)
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse time: %v", err)
+	}
+
+	return t, nil
+}
+
+// CalDAV events are identified by the path of their calendar object
+func (calendar *CalDAVCalendar) CreateEvent(calendarId string, details CalendarEventDetails) (string, error) {
+	uid := details.UID
+	if len(uid) == 0 {
+		uid = uuid.New().String()
+	}
+	eventPath := path.Join(calendarId, url.PathEscape(uid)+".ics")
+
+	if err := calendar.UpdateEvent(calendarId, eventPath, details); err != nil {
+		return "", err
+	}
+
+	return eventPath, nil
+}
+
+func (calendar *CalDAVCalendar) UpdateEvent(calendarId string, eventId string, details CalendarEventDetails) error {
+	_, caldavClient, err := calendar.getClients()
+	if err != nil {
+		return err
+	}
+
+	uid := details.UID
+	if len(uid) == 0 {
+		uid = strings.TrimSuffix(path.Base(eventId), ".ics")
+	}
+
+	vevent := ical.NewEvent()
+	vevent.Props.SetText(ical.PropUID, uid)
+	vevent.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	vevent.Props.SetDateTime(ical.PropDateTimeStart, details.StartDate.UTC())
+	vevent.Props.SetDateTime(ical.PropDateTimeEnd, details.EndDate.UTC())
+	vevent.Props.SetText(ical.PropSummary, details.Summary)
+	if len(details.Description) > 0 {
+		vevent.Props.SetText(ical.PropDescription, details.Description)
+	}
+	if len(details.Location) > 0 {
+		vevent.Props.SetText(ical.PropLocation, details.Location)
+	}
+
+	cal := ics.NewCalendar("")
+	cal.Children = append(cal.Children, vevent.Component)
+
+	_, err = caldavClient.PutCalendarObject(context.Background(), eventId, cal)
+	return err
+}
+
+func (calendar *CalDAVCalendar) DeleteEvent(calendarId string, eventId string) error {
+	webdavClient, _, err := calendar.getClients()
+	if err != nil {
+		return err
+	}
+
+	return webdavClient.RemoveAll(context.Background(), eventId)
+}
@@ -0,0 +1,99 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Events as returned by a CalDAV server for an expanded calendar query
+const testCalDAVEvents = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//test//EN
+BEGIN:VEVENT
+UID:meeting
+DTSTART;TZID=Europe/Berlin:20240506T090000
+DTEND;TZID=Europe/Berlin:20240506T100000
+SUMMARY:Meeting
+END:VEVENT
+BEGIN:VEVENT
+UID:duration
+DTSTART:20240507T150000Z
+DURATION:PT45M
+END:VEVENT
+BEGIN:VEVENT
+DTSTART:20240508T150000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:all-day
+DTSTART;VALUE=DATE:20240509
+SUMMARY:Holiday
+END:VEVENT
+BEGIN:VEVENT
+UID:no-start
+DTEND:20240510T150000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestGetCalDAVCalendarEvent(t *testing.T) {
+	cal, err := ical.NewDecoder(strings.NewReader(strings.ReplaceAll(testCalDAVEvents, "\n", "\r\n"))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []models.CalendarEvent
+	for _, component := range cal.Children {
+		if event, ok := getCalDAVCalendarEvent(component); ok {
+			events = append(events, event)
+		}
+	}
+
+	// The all day event and the event without a start are skipped
+	expected := []models.CalendarEvent{
+		{
+			Id:        "meeting",
+			Summary:   "Meeting",
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 5, 6, 7, 0, 0, 0, time.UTC)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)),
+		},
+		{
+			Id:        "duration",
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 5, 7, 15, 0, 0, 0, time.UTC)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 5, 7, 15, 45, 0, 0, time.UTC)),
+		},
+		{
+			StartDate: primitive.NewDateTimeFromTime(time.Date(2024, 5, 8, 15, 0, 0, 0, time.UTC)),
+			EndDate:   primitive.NewDateTimeFromTime(time.Date(2024, 5, 8, 15, 0, 0, 0, time.UTC)),
+		},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected event %d to be %+v, got %+v", i, expected[i], events[i])
+		}
+	}
+}
+
+func TestCalDAVCalendarRejectsInternalServers(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+	password, err := utils.Encrypt("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, serverUrl := range []string{"http://localhost:5232", "http://127.0.0.1/dav", "http://169.254.169.254/"} {
+		calendar := CalDAVCalendar{models.CalDAVCalendarAuth{ServerUrl: serverUrl, Username: "user", Password: password}}
+		if _, err := calendar.GetCalendarList(); !errors.Is(err, ErrInternalAddress) {
+			t.Errorf("expected %s to be rejected as internal, got %v", serverUrl, err)
+		}
+	}
+}
@@ -65,14 +65,16 @@ func GetUsersCalendarEvents(user *models.User, accounts models.Set[string], time
 	calendarListChan := make(chan GetCalendarListData)
 	calendarEventsChan := make(chan GetCalendarEventsData)
 
-	// Get calendar lists
+	// Get calendar lists. The providers are kept to get the events with, so that providers can reuse what they fetched
 	numCalendarListRequests := 0
+	calendarProviders := make(map[string]*CalendarProvider)
 	for _, account := range user.CalendarAccounts {
 		calendarProvider := GetCalendarProvider(account)
 		calendarAccountKey := utils.GetCalendarAccountKey(account.Email, account.CalendarType)
 
 		// Get secondary account calendars
 		if _, ok := accounts[calendarAccountKey]; ok || returnAllAccounts {
+			calendarProviders[calendarAccountKey] = &calendarProvider
 			go GetCalendarListAsync(calendarAccountKey, &calendarProvider, calendarListChan)
 			numCalendarListRequests++
 
@@ -98,7 +100,7 @@ func GetUsersCalendarEvents(user *models.User, accounts models.Set[string], time
 
 		// Edit subcalendars map
 		account := user.CalendarAccounts[calendarListData.CalendarAccountKey]
-		calendarProvider := GetCalendarProvider(account)
+		calendarProvider := calendarProviders[calendarListData.CalendarAccountKey]
 		if account.SubCalendars == nil {
 			account.SubCalendars = &calendarListData.CalendarList
 			user.CalendarAccounts[calendarListData.CalendarAccountKey] = account
@@ -129,7 +131,7 @@ func GetUsersCalendarEvents(user *models.User, accounts models.Set[string], time
 		user.CalendarAccounts[calendarListData.CalendarAccountKey] = account
 
 		for id := range *account.SubCalendars {
-			go GetCalendarEventsAsync(calendarListData.CalendarAccountKey, &calendarProvider, id, timeMin, timeMax, calendarEventsChan)
+			go GetCalendarEventsAsync(calendarListData.CalendarAccountKey, calendarProvider, id, timeMin, timeMax, calendarEventsChan)
 			numCalendarEventsRequests++
 		}
 	}
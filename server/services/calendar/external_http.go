@@ -0,0 +1,106 @@
+package calendar
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Returned when a url that a user gave us points at the server itself or the network it runs in
+var ErrInternalAddress = errors.New("calendar url points to an internal address")
+
+// Maximum number of redirects followed when fetching a url that a user gave us
+const maxExternalRedirects = 5
+
+// Address ranges that are internal even though net.IP doesn't report them as private
+var internalNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "This" network
+	mustParseCIDR("100.64.0.0/10"), // Carrier-grade NAT, used for internal addresses by some cloud providers
+	mustParseCIDR("192.0.0.0/24"),  // IETF protocol assignments
+	mustParseCIDR("198.18.0.0/15"), // Benchmarking
+}
+
+// Returns an http client for fetching urls that users give us, e.g. calendar feeds. It refuses to connect to loopback,
+// private, and link-local addresses, so that users can't make the server reach cloud metadata endpoints or internal services.
+// The check runs on the resolved address of every connection, including redirects, so DNS can't be used to get around it
+func newExternalHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: rejectInternalAddress,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the internal address on our behalf
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: checkExternalRedirect,
+	}
+}
+
+// Returns ErrInternalAddress if the url can't be fetched with an external http client, without resolving its host
+func checkExternalUrl(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("unsupported calendar url scheme")
+	}
+
+	host := u.Hostname()
+	if host == "localhost" || len(host) == 0 {
+		return ErrInternalAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && isInternalIP(ip) {
+		return ErrInternalAddress
+	}
+
+	return nil
+}
+
+func checkExternalRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxExternalRedirects {
+		return errors.New("too many redirects")
+	}
+
+	return checkExternalUrl(req.URL)
+}
+
+func rejectInternalAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isInternalIP(ip) {
+		return ErrInternalAddress
+	}
+
+	return nil
+}
+
+func isInternalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"fd00:ec2::254", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, test := range tests {
+		if actual := isInternalIP(net.ParseIP(test.ip)); actual != test.expected {
+			t.Errorf("expected isInternalIP(%s) to be %v, got %v", test.ip, test.expected, actual)
+		}
+	}
+}
+
+func TestFetchICSCalendarRejectsInternalAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte(strings.ReplaceAll(testICSFeed, "\n", "\r\n")))
+	}))
+	defer server.Close()
+
+	// The test server listens on a loopback address, like an internal service would
+	for _, feedUrl := range []string{
+		server.URL,
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		"webcal://169.254.169.254/latest/meta-data",
+	} {
+		if _, err := FetchICSCalendar(feedUrl); !errors.Is(err, ErrInternalAddress) {
+			t.Errorf("expected fetching %s to be rejected, got %v", feedUrl, err)
+		}
+	}
+	if requested {
+		t.Error("expected the internal server not to be requested")
+	}
+
+	if _, err := FetchICSCalendar("file:///etc/passwd"); err == nil {
+		t.Error("expected non http urls to be rejected")
+	}
+}
+
+func TestExternalHttpClientRejectsInternalRedirects(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://169.254.169.254/latest/meta-data", nil)
+	if err := checkExternalRedirect(req, []*http.Request{httptest.NewRequest(http.MethodGet, "https://example.com", nil)}); !errors.Is(err, ErrInternalAddress) {
+		t.Errorf("expected redirects to internal addresses to be rejected, got %v", err)
+	}
+
+	// Hosts that resolve to internal addresses are rejected when connecting, even if the url looks external
+	if err := rejectInternalAddress("tcp", "10.0.0.1:443", nil); !errors.Is(err, ErrInternalAddress) {
+		t.Errorf("expected connecting to a private address to be rejected, got %v", err)
+	}
+	if err := rejectInternalAddress("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("expected connecting to a public address to be allowed, got %v", err)
+	}
+}
@@ -1,12 +1,15 @@
 package calendar
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"schej.it/server/errs"
 	"schej.it/server/logger"
@@ -152,3 +155,92 @@ func (calendar *GoogleCalendar) GetCalendarEvents(calendarId string, timeMin tim
 
 	return calendarEvents, nil
 }
+
+func (calendar *GoogleCalendar) CreateEvent(calendarId string, details CalendarEventDetails) (string, error) {
+	return calendar.writeEvent(
+		"POST",
+		fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events", url.PathEscape(calendarId)),
+		details,
+	)
+}
+
+func (calendar *GoogleCalendar) UpdateEvent(calendarId string, eventId string, details CalendarEventDetails) error {
+	_, err := calendar.writeEvent(
+		"PATCH",
+		fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events/%s", url.PathEscape(calendarId), url.PathEscape(eventId)),
+		details,
+	)
+	return err
+}
+
+func (calendar *GoogleCalendar) DeleteEvent(calendarId string, eventId string) error {
+	req, _ := http.NewRequest(
+		"DELETE",
+		fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events/%s", url.PathEscape(calendarId), url.PathEscape(eventId)),
+		nil,
+	)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", calendar.AccessToken))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The event was already deleted by the user
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		var res struct {
+			Error *errs.GoogleAPIError `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err == nil && res.Error != nil {
+			return getGoogleWriteError(resp, res.Error)
+		}
+		return fmt.Errorf("failed to delete google calendar event: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sends the event details to the given events endpoint and returns the id of the resulting event
+func (calendar *GoogleCalendar) writeEvent(method string, endpoint string, details CalendarEventDetails) (string, error) {
+	body, _ := json.Marshal(bson.M{
+		"summary":     details.Summary,
+		"description": details.Description,
+		"location":    details.Location,
+		"start":       bson.M{"dateTime": details.StartDate.UTC().Format(time.RFC3339)},
+		"end":         bson.M{"dateTime": details.EndDate.UTC().Format(time.RFC3339)},
+	})
+	req, _ := http.NewRequest(method, endpoint, bytes.NewBuffer(body))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", calendar.AccessToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Id    string               `json:"id"`
+		Error *errs.GoogleAPIError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+
+	if res.Error != nil {
+		return "", getGoogleWriteError(resp, res.Error)
+	}
+
+	return res.Id, nil
+}
+
+// Wraps the error of a request that writes events in ErrInsufficientScope if the account was only granted read only scopes
+func getGoogleWriteError(resp *http.Response, apiError *errs.GoogleAPIError) error {
+	if resp.StatusCode == http.StatusForbidden && strings.Contains(resp.Header.Get("WWW-Authenticate"), "insufficient_scope") {
+		return fmt.Errorf("%w: %v", ErrInsufficientScope, apiError)
+	}
+	return apiError
+}
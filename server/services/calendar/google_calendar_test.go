@@ -0,0 +1,25 @@
+package calendar
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"schej.it/server/errs"
+)
+
+func TestGetGoogleWriteError(t *testing.T) {
+	apiError := &errs.GoogleAPIError{Code: http.StatusForbidden, Message: "Request had insufficient authentication scopes."}
+
+	insufficientScope := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	insufficientScope.Header.Set("WWW-Authenticate", `Bearer realm="https://accounts.google.com/", error="insufficient_scope", scope="https://www.googleapis.com/auth/calendar.events"`)
+	if err := getGoogleWriteError(insufficientScope, apiError); !errors.Is(err, ErrInsufficientScope) {
+		t.Errorf("expected a read only account to need to be reconnected, got %v", err)
+	}
+
+	// Lacking write access to the calendar itself isn't fixed by reconnecting
+	noAccess := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	if err := getGoogleWriteError(noAccess, apiError); errors.Is(err, ErrInsufficientScope) {
+		t.Errorf("expected other permission errors to be returned as is, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// ICS feeds only contain a single calendar, so it is always identified by this id
+const ICSSubCalendarId = "ics"
+
+// Maximum size of an ics feed that will be downloaded
+const maxICSFeedSize = 10 << 20
+
+var icsHttpClient = newExternalHttpClient(15 * time.Second)
+
+type ICSCalendar struct {
+	models.ICSCalendarAuth
+
+	// Name the user gave the calendar when adding it
+	Name string
+
+	// The feed is only downloaded once per provider, since getting the calendar list and the events both need it
+	mutex sync.Mutex
+	cal   *ical.Calendar
+}
+
+func (calendar *ICSCalendar) GetCalendarList() (map[string]models.SubCalendar, error) {
+	// Make sure the feed can still be fetched so errors show up in the calendar list like other providers
+	if _, err := calendar.fetchCalendar(); err != nil {
+		return nil, err
+	}
+
+	return map[string]models.SubCalendar{
+		ICSSubCalendarId: {
+			Name:    calendar.Name,
+			Enabled: utils.TruePtr(),
+		},
+	}, nil
+}
+
+func (calendar *ICSCalendar) GetCalendarEvents(calendarId string, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error) {
+	cal, err := calendar.fetchCalendar()
+	if err != nil {
+		return nil, err
+	}
+
+	return getICSCalendarEvents(cal, calendarId, timeMin, timeMax), nil
+}
+
+func (calendar *ICSCalendar) CreateEvent(calendarId string, details CalendarEventDetails) (string, error) {
+	return "", ErrReadOnlyCalendar
+}
+
+func (calendar *ICSCalendar) UpdateEvent(calendarId string, eventId string, details CalendarEventDetails) error {
+	return ErrReadOnlyCalendar
+}
+
+func (calendar *ICSCalendar) DeleteEvent(calendarId string, eventId string) error {
+	return ErrReadOnlyCalendar
+}
+
+// Downloads and decodes the ics feed, reusing the feed if it was already downloaded
+func (calendar *ICSCalendar) fetchCalendar() (*ical.Calendar, error) {
+	calendar.mutex.Lock()
+	defer calendar.mutex.Unlock()
+
+	if calendar.cal != nil {
+		return calendar.cal, nil
+	}
+
+	feedUrl, err := utils.Decrypt(calendar.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	cal, err := FetchICSCalendar(feedUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.cal = cal
+	return cal, nil
+}
+
+// Downloads and decodes the ics feed at the given url, with its time zones resolved to IANA names. webcal:// urls are
+// fetched over https. Urls pointing to internal addresses return ErrInternalAddress
+func FetchICSCalendar(feedUrl string) (*ical.Calendar, error) {
+	if strings.HasPrefix(feedUrl, "webcal://") {
+		feedUrl = "https://" + strings.TrimPrefix(feedUrl, "webcal://")
+	}
+	u, err := url.Parse(feedUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExternalUrl(u); err != nil {
+		return nil, err
+	}
+
+	resp, err := icsHttpClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch calendar: %s", resp.Status)
+	}
+
+	cal, err := ical.NewDecoder(io.LimitReader(resp.Body, maxICSFeedSize)).Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	resolveICSTimeZones(cal)
+	return cal, nil
+}
+
+// Returns the events in the calendar that overlap with the given time range, with recurring events expanded
+func getICSCalendarEvents(cal *ical.Calendar, calendarId string, timeMin time.Time, timeMax time.Time) []models.CalendarEvent {
+	// Modified instances of a recurring event are their own VEVENT with the same UID and a RECURRENCE-ID,
+	// so keep track of them to skip the original occurrence when expanding the recurrence
+	overriddenInstances := make(map[string]models.Set[int64])
+	for _, event := range cal.Events() {
+		recurrenceIdProp := event.Props.Get(ical.PropRecurrenceID)
+		uidProp := event.Props.Get(ical.PropUID)
+		if recurrenceIdProp == nil || uidProp == nil {
+			continue
+		}
+
+		recurrenceId, err := recurrenceIdProp.DateTime(time.UTC)
+		if err != nil {
+			continue
+		}
+		if _, ok := overriddenInstances[uidProp.Value]; !ok {
+			overriddenInstances[uidProp.Value] = make(models.Set[int64])
+		}
+		overriddenInstances[uidProp.Value][recurrenceId.Unix()] = struct{}{}
+	}
+
+	calendarEvents := make([]models.CalendarEvent, 0)
+	for _, event := range cal.Events() {
+		startProp := event.Props.Get(ical.PropDateTimeStart)
+		if startProp == nil {
+			continue
+		}
+
+		// Filter out all day events
+		if startProp.ValueType() == ical.ValueDate || !strings.Contains(startProp.Value, "T") {
+			continue
+		}
+
+		// Filter out cancelled events
+		if status, err := event.Status(); err == nil && status == ical.EventCancelled {
+			continue
+		}
+
+		startTime, err := event.DateTimeStart(time.UTC)
+		if err != nil {
+			continue
+		}
+		endTime, err := event.DateTimeEnd(time.UTC)
+		if err != nil {
+			continue
+		}
+		duration := endTime.Sub(startTime)
+
+		var uid, summary string
+		if prop := event.Props.Get(ical.PropUID); prop != nil {
+			uid = prop.Value
+		}
+		if prop := event.Props.Get(ical.PropSummary); prop != nil {
+			summary, _ = prop.Text()
+		}
+		free := false
+		if prop := event.Props.Get(ical.PropTransparency); prop != nil {
+			free = prop.Value == "TRANSPARENT"
+		}
+
+		// Get the start time of every occurrence of the event
+		isOverride := event.Props.Get(ical.PropRecurrenceID) != nil
+		isRecurring := isOverride
+		startTimes := []time.Time{startTime}
+		if !isOverride {
+			recurrenceSet, err := event.RecurrenceSet(time.UTC)
+			if err != nil {
+				continue
+			}
+			if recurrenceSet != nil {
+				isRecurring = true
+				// Include occurrences that start before timeMin but are still ongoing
+				startTimes = recurrenceSet.Between(timeMin.Add(-duration), timeMax, true)
+			}
+		}
+
+		for _, occurrenceStart := range startTimes {
+			if _, ok := overriddenInstances[uid][occurrenceStart.Unix()]; ok && !isOverride {
+				continue
+			}
+
+			occurrenceEnd := occurrenceStart.Add(duration)
+			if !occurrenceEnd.After(timeMin) || !occurrenceStart.Before(timeMax) {
+				continue
+			}
+
+			id := uid
+			if isRecurring {
+				id = fmt.Sprintf("%s_%s", uid, occurrenceStart.UTC().Format("20060102T150405Z"))
+			}
+
+			calendarEvents = append(calendarEvents, models.CalendarEvent{
+				Id:         id,
+				CalendarId: calendarId,
+				Summary:    summary,
+				StartDate:  primitive.NewDateTimeFromTime(occurrenceStart),
+				EndDate:    primitive.NewDateTimeFromTime(occurrenceEnd),
+				Free:       free,
+			})
+		}
+	}
+
+	sort.SliceStable(calendarEvents, func(i, j int) bool {
+		return calendarEvents[i].StartDate < calendarEvents[j].StartDate
+	})
+
+	return calendarEvents
+}
@@ -0,0 +1,181 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+const testICSFeed = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//test//EN
+BEGIN:VEVENT
+UID:weekly
+DTSTART;TZID=America/New_York:20240506T090000
+DTEND;TZID=America/New_York:20240506T100000
+RRULE:FREQ=WEEKLY;COUNT=4
+EXDATE;TZID=America/New_York:20240513T090000
+SUMMARY:Lecture
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly
+RECURRENCE-ID;TZID=America/New_York:20240520T090000
+DTSTART;TZID=America/New_York:20240520T110000
+DTEND;TZID=America/New_York:20240520T120000
+SUMMARY:Lecture (moved)
+END:VEVENT
+BEGIN:VEVENT
+UID:single
+DTSTART:20240507T150000Z
+DURATION:PT30M
+SUMMARY:Office hours
+TRANSP:TRANSPARENT
+END:VEVENT
+BEGIN:VEVENT
+UID:all-day
+DTSTART;VALUE=DATE:20240508
+SUMMARY:Holiday
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled
+DTSTART:20240509T150000Z
+DTEND:20240509T160000Z
+STATUS:CANCELLED
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestGetICSCalendarEvents(t *testing.T) {
+	cal, err := ical.NewDecoder(strings.NewReader(strings.ReplaceAll(testICSFeed, "\n", "\r\n"))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeMin := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	timeMax := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := getICSCalendarEvents(cal, ICSSubCalendarId, timeMin, timeMax)
+
+	// May 6 and May 27 occurrences (May 13 is excluded and May 20 is moved), the moved occurrence, and office hours
+	expected := []struct {
+		summary string
+		start   time.Time
+		free    bool
+	}{
+		{"Lecture", time.Date(2024, 5, 6, 13, 0, 0, 0, time.UTC), false},
+		{"Office hours", time.Date(2024, 5, 7, 15, 0, 0, 0, time.UTC), true},
+		{"Lecture (moved)", time.Date(2024, 5, 20, 15, 0, 0, 0, time.UTC), false},
+		{"Lecture", time.Date(2024, 5, 27, 13, 0, 0, 0, time.UTC), false},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		if events[i].Summary != e.summary || !events[i].StartDate.Time().Equal(e.start) || events[i].Free != e.free {
+			t.Errorf("expected event %d to be %s at %v (free: %v), got %s at %v (free: %v)",
+				i, e.summary, e.start, e.free, events[i].Summary, events[i].StartDate.Time().UTC(), events[i].Free)
+		}
+	}
+	if events[1].EndDate.Time().Sub(events[1].StartDate.Time()) != 30*time.Minute {
+		t.Errorf("expected duration to be used when there is no end time")
+	}
+}
+
+func TestICSCalendarReusesFeed(t *testing.T) {
+	cal, err := ical.NewDecoder(strings.NewReader(strings.ReplaceAll(testICSFeed, "\n", "\r\n"))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The url can't be decrypted, so the calendar list and events can only come from the feed that was already fetched
+	calendar := &ICSCalendar{Name: "Classes", cal: cal}
+	if _, err := calendar.GetCalendarList(); err != nil {
+		t.Errorf("expected the calendar list to reuse the fetched feed, got %v", err)
+	}
+	timeMin := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if events, err := calendar.GetCalendarEvents(ICSSubCalendarId, timeMin, timeMin.AddDate(0, 1, 0)); err != nil || len(events) == 0 {
+		t.Errorf("expected the events to come from the fetched feed, got %v, %v", events, err)
+	}
+}
+
+// Outlook exports use Windows time zone names, and other clients define custom time zones
+const testNonIANATimeZonesFeed = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//test//EN
+BEGIN:VTIMEZONE
+TZID:/mozilla.org/20050126_1/America/Chicago
+X-LIC-LOCATION:America/Chicago
+BEGIN:STANDARD
+DTSTART:19701101T020000
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0600
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VTIMEZONE
+TZID:Custom Time Zone
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0300
+TZOFFSETTO:+0300
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:windows
+DTSTART;TZID=W. Europe Standard Time:20240506T090000
+DTEND;TZID=W. Europe Standard Time:20240506T100000
+RRULE:FREQ=WEEKLY;COUNT=2
+EXDATE;TZID=W. Europe Standard Time:20240513T090000
+SUMMARY:Windows time zone
+END:VEVENT
+BEGIN:VEVENT
+UID:mozilla
+DTSTART;TZID=/mozilla.org/20050126_1/America/Chicago:20240507T090000
+DTEND;TZID=/mozilla.org/20050126_1/America/Chicago:20240507T100000
+SUMMARY:Custom time zone with a location
+END:VEVENT
+BEGIN:VEVENT
+UID:custom
+DTSTART;TZID=Custom Time Zone:20240508T090000
+DTEND;TZID=Custom Time Zone:20240508T100000
+SUMMARY:Custom time zone with an offset
+END:VEVENT
+BEGIN:VEVENT
+UID:unknown
+DTSTART;TZID=Unknown Time Zone:20240509T090000
+DTEND;TZID=Unknown Time Zone:20240509T100000
+SUMMARY:Unknown time zone
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestGetICSCalendarEventsWithNonIANATimeZones(t *testing.T) {
+	cal, err := ical.NewDecoder(strings.NewReader(strings.ReplaceAll(testNonIANATimeZonesFeed, "\n", "\r\n"))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolveICSTimeZones(cal)
+
+	timeMin := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	timeMax := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := getICSCalendarEvents(cal, ICSSubCalendarId, timeMin, timeMax)
+
+	// Time zones that can't be resolved are read as UTC rather than dropping the event
+	expected := []struct {
+		summary string
+		start   time.Time
+	}{
+		{"Windows time zone", time.Date(2024, 5, 6, 7, 0, 0, 0, time.UTC)},
+		{"Custom time zone with a location", time.Date(2024, 5, 7, 14, 0, 0, 0, time.UTC)},
+		{"Custom time zone with an offset", time.Date(2024, 5, 8, 6, 0, 0, 0, time.UTC)},
+		{"Unknown time zone", time.Date(2024, 5, 9, 9, 0, 0, 0, time.UTC)},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		if events[i].Summary != e.summary || !events[i].StartDate.Time().Equal(e.start) {
+			t.Errorf("expected event %d to be %s at %v, got %s at %v", i, e.summary, e.start, events[i].Summary, events[i].StartDate.Time().UTC())
+		}
+	}
+}
@@ -3,6 +3,8 @@ package calendar
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -49,11 +51,11 @@ func (calendar *OutlookCalendar) GetCalendarList() (map[string]models.SubCalenda
 }
 
 func (calendar *OutlookCalendar) GetCalendarEvents(calendarId string, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error) {
-	url := fmt.Sprintf("https://graph.microsoft.com/v1.0/me/calendars/%s/calendarview?startdatetime=%s&enddatetime=%s&$select=id,subject,start,end,showAs",
-		calendarId,
+	apiUrl := fmt.Sprintf("https://graph.microsoft.com/v1.0/me/calendars/%s/calendarview?startdatetime=%s&enddatetime=%s&$select=id,subject,start,end,showAs",
+		url.PathEscape(calendarId),
 		timeMin.Format(time.RFC3339),
 		timeMax.Format(time.RFC3339))
-	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "GET", url, nil)
+	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "GET", apiUrl, nil)
 	defer response.Body.Close()
 
 	responseBody := struct {
@@ -105,3 +107,90 @@ func (calendar *OutlookCalendar) GetCalendarEvents(calendarId string, timeMin ti
 
 	return calendarEvents, nil
 }
+
+func (calendar *OutlookCalendar) CreateEvent(calendarId string, details CalendarEventDetails) (string, error) {
+	body := getOutlookEventBody(details)
+	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "POST", fmt.Sprintf("https://graph.microsoft.com/v1.0/me/calendars/%s/events", url.PathEscape(calendarId)), &body)
+	defer response.Body.Close()
+
+	responseBody := struct {
+		Id    string `json:"id"`
+		Error bson.M `json:"error"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
+		return "", err
+	}
+
+	if responseBody.Error != nil {
+		return "", getOutlookWriteError(response, fmt.Errorf("error creating Outlook event: %v", responseBody.Error))
+	}
+
+	return responseBody.Id, nil
+}
+
+func (calendar *OutlookCalendar) UpdateEvent(calendarId string, eventId string, details CalendarEventDetails) error {
+	body := getOutlookEventBody(details)
+	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "PATCH", fmt.Sprintf("https://graph.microsoft.com/v1.0/me/events/%s", url.PathEscape(eventId)), &body)
+	defer response.Body.Close()
+
+	responseBody := struct {
+		Error bson.M `json:"error"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
+		return err
+	}
+
+	if responseBody.Error != nil {
+		return getOutlookWriteError(response, fmt.Errorf("error updating Outlook event: %v", responseBody.Error))
+	}
+
+	return nil
+}
+
+func (calendar *OutlookCalendar) DeleteEvent(calendarId string, eventId string) error {
+	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "DELETE", fmt.Sprintf("https://graph.microsoft.com/v1.0/me/events/%s", url.PathEscape(eventId)), nil)
+	defer response.Body.Close()
+
+	// The event was already deleted by the user
+	if response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		return getOutlookWriteError(response, fmt.Errorf("error deleting Outlook event: %s", response.Status))
+	}
+
+	return nil
+}
+
+// Wraps the error of a request that writes events in ErrInsufficientScope if the account was only granted Calendars.Read
+func getOutlookWriteError(response *http.Response, err error) error {
+	if response.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %v", ErrInsufficientScope, err)
+	}
+	return err
+}
+
+func getOutlookEventBody(details CalendarEventDetails) bson.M {
+	// Custom time format for Outlook date-time strings
+	const outlookTimeFormat = "2006-01-02T15:04:05.0000000"
+
+	return bson.M{
+		"subject": details.Summary,
+		"body": bson.M{
+			"contentType": "text",
+			"content":     details.Description,
+		},
+		"location": bson.M{
+			"displayName": details.Location,
+		},
+		"start": bson.M{
+			"dateTime": details.StartDate.UTC().Format(outlookTimeFormat),
+			"timeZone": "UTC",
+		},
+		"end": bson.M{
+			"dateTime": details.EndDate.UTC().Format(outlookTimeFormat),
+			"timeZone": "UTC",
+		},
+	}
+}
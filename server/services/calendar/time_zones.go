@@ -0,0 +1,188 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-ical"
+	"schej.it/server/utils"
+)
+
+// IANA names of the Windows time zone names that Outlook and Exchange use as TZIDs, from the CLDR windowsZones mapping
+var windowsTimeZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"UTC-11":                          "Etc/GMT+11",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time (Mexico)":  "America/Tijuana",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time (Mexico)": "America/Mazatlan",
+	"Mountain Standard Time":          "America/Denver",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time":           "America/New_York",
+	"Eastern Standard Time (Mexico)":  "America/Cancun",
+	"US Eastern Standard Time":        "America/Indianapolis",
+	"Venezuela Standard Time":         "America/Caracas",
+	"Atlantic Standard Time":          "America/Halifax",
+	"SA Western Standard Time":        "America/La_Paz",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"SA Eastern Standard Time":        "America/Cayenne",
+	"Greenland Standard Time":         "America/Godthab",
+	"UTC-02":                          "Etc/GMT+2",
+	"Azores Standard Time":            "Atlantic/Azores",
+	"UTC":                             "Etc/UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Arabic Standard Time":            "Asia/Baghdad",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Russian Standard Time":           "Europe/Moscow",
+	"E. Africa Standard Time":         "Africa/Nairobi",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Afghanistan Standard Time":       "Asia/Kabul",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"West Asia Standard Time":         "Asia/Tashkent",
+	"India Standard Time":             "Asia/Calcutta",
+	"Sri Lanka Standard Time":         "Asia/Colombo",
+	"Nepal Standard Time":             "Asia/Katmandu",
+	"Central Asia Standard Time":      "Asia/Almaty",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"Myanmar Standard Time":           "Asia/Rangoon",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"West Pacific Standard Time":      "Pacific/Port_Moresby",
+	"Central Pacific Standard Time":   "Pacific/Guadalcanal",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"UTC+12":                          "Etc/GMT-12",
+	"Fiji Standard Time":              "Pacific/Fiji",
+	"Tonga Standard Time":             "Pacific/Tongatapu",
+}
+
+// Replaces the TZIDs of the event times in the calendar with IANA names, so that they can be parsed. TZIDs that can't
+// be resolved are removed, which leaves the times floating so that they are read as UTC instead of being dropped
+func resolveICSTimeZones(cal *ical.Calendar) {
+	timeZones := make(map[string]*ical.Component)
+	for _, child := range cal.Children {
+		if child.Name == ical.CompTimezone {
+			if tzid := child.Props.Get(ical.PropTimezoneID); tzid != nil {
+				timeZones[tzid.Value] = child
+			}
+		}
+	}
+
+	resolved := make(map[string]string)
+	for _, event := range cal.Events() {
+		for _, props := range event.Props {
+			for _, prop := range props {
+				tzid := prop.Params.Get(ical.PropTimezoneID)
+				if len(tzid) == 0 {
+					continue
+				}
+
+				name, ok := resolved[tzid]
+				if !ok {
+					name = resolveTimeZone(tzid, timeZones[tzid])
+					resolved[tzid] = name
+				}
+				if len(name) > 0 {
+					prop.Params.Set(ical.PropTimezoneID, name)
+				} else {
+					prop.Params.Del(ical.PropTimezoneID)
+				}
+			}
+		}
+	}
+}
+
+// Returns the IANA name of the time zone with the given TZID, using its VTIMEZONE definition if it isn't nil.
+// Returns an empty string if the time zone can't be resolved
+func resolveTimeZone(tzid string, timeZone *ical.Component) string {
+	if utils.IsValidTimeZone(tzid) {
+		return tzid
+	}
+	if name, ok := windowsTimeZones[tzid]; ok {
+		return name
+	}
+
+	// Some clients name the location of custom time zones, e.g. "/mozilla.org/20050126_1/America/New_York"
+	if timeZone != nil {
+		if location := timeZone.Props.Get("X-LIC-LOCATION"); location != nil && utils.IsValidTimeZone(location.Value) {
+			return location.Value
+		}
+	}
+	segments := strings.Split(strings.Trim(tzid, "/"), "/")
+	for n := 3; n >= 2; n-- {
+		if len(segments) >= n {
+			if name := strings.Join(segments[len(segments)-n:], "/"); utils.IsValidTimeZone(name) {
+				return name
+			}
+		}
+	}
+
+	// Otherwise use the standard offset of the definition, which is only off by the daylight saving time shift
+	if timeZone != nil {
+		for _, child := range timeZone.Children {
+			if child.Name != ical.CompTimezoneStandard {
+				continue
+			}
+			if offset := child.Props.Get(ical.PropTimezoneOffsetTo); offset != nil {
+				return getFixedOffsetTimeZone(offset.Value)
+			}
+		}
+	}
+
+	return ""
+}
+
+// Returns the Etc/GMT time zone with the given UTC offset (e.g. "-0500"), or an empty string if there is none because
+// the offset isn't a whole number of hours
+func getFixedOffsetTimeZone(offset string) string {
+	if len(offset) < 5 || (offset[0] != '+' && offset[0] != '-') {
+		return ""
+	}
+	hours, err := strconv.Atoi(offset[1:3])
+	if err != nil || strings.Trim(offset[3:], "0") != "" {
+		return ""
+	}
+	if hours == 0 {
+		return "Etc/GMT"
+	}
+
+	// The sign of Etc/GMT time zones is inverted, e.g. Etc/GMT+5 is 5 hours behind UTC
+	sign := "-"
+	if offset[0] == '-' {
+		sign = "+"
+	}
+	return fmt.Sprintf("Etc/GMT%s%d", sign, hours)
+}
@@ -1,6 +1,7 @@
 package calendar
 
 import (
+	"errors"
 	"time"
 
 	"schej.it/server/models"
@@ -9,8 +10,31 @@ import (
 type CalendarProvider interface {
 	GetCalendarList() (map[string]models.SubCalendar, error)
 	GetCalendarEvents(calendarId string, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error)
+
+	// Creates an event in the given calendar and returns the id of the created event
+	CreateEvent(calendarId string, details CalendarEventDetails) (string, error)
+	UpdateEvent(calendarId string, eventId string, details CalendarEventDetails) error
+	DeleteEvent(calendarId string, eventId string) error
+}
+
+// The details of an event to write to a user's calendar
+type CalendarEventDetails struct {
+	// Used as the iCalendar UID by providers that let the client choose it, so the event matches the invites sent to attendees
+	UID string
+
+	Summary     string
+	Description string
+	Location    string
+	StartDate   time.Time
+	EndDate     time.Time
 }
 
+// Returned by providers that can only read events, e.g. ics feeds
+var ErrReadOnlyCalendar = errors.New("calendar is read only")
+
+// Returned when the calendar account was connected without permission to write events, so it has to be reconnected
+var ErrInsufficientScope = errors.New("calendar account wasn't granted permission to write events")
+
 func GetCalendarProvider(calendarAccount models.CalendarAccount) CalendarProvider {
 	switch calendarAccount.CalendarType {
 	case models.GoogleCalendarType:
@@ -25,6 +49,15 @@ func GetCalendarProvider(calendarAccount models.CalendarAccount) CalendarProvide
 		return &AppleCalendar{
 			AppleCalendarAuth: *calendarAccount.AppleCalendarAuth,
 		}
+	case models.CalDAVCalendarType:
+		return &CalDAVCalendar{
+			CalDAVCalendarAuth: *calendarAccount.CalDAVCalendarAuth,
+		}
+	case models.ICSCalendarType:
+		return &ICSCalendar{
+			ICSCalendarAuth: *calendarAccount.ICSCalendarAuth,
+			Name:            calendarAccount.Email,
+		}
 	}
 	return nil
 }
@@ -0,0 +1,90 @@
+package conferencing
+
+import (
+	"errors"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/auth"
+	"schej.it/server/utils"
+)
+
+// Creates meeting links for a video conferencing service
+type Provider interface {
+	CreateMeeting(options MeetingOptions) (Meeting, error)
+
+	// Removes a meeting that was created as a calendar event. The user is the one the calendar event belongs to
+	DeleteMeeting(user *models.User, calendarEvent *models.ConferenceCalendarEvent) error
+
+	// Whether a start and end time is needed to create a meeting, e.g. because the meeting is created as a calendar event
+	RequiresTime() bool
+}
+
+type MeetingOptions struct {
+	// The user creating the meeting, whose connected calendar accounts are used by providers that need them
+	User *models.User
+
+	Title     string
+	StartTime time.Time
+	EndTime   time.Time
+
+	// The link to use for the custom link provider
+	Link string
+}
+
+type Meeting struct {
+	Link string
+
+	// Nil for providers that don't create calendar events
+	CalendarEvent *models.ConferenceCalendarEvent
+}
+
+// Returned when the user doesn't have the calendar account needed to create a meeting with the provider
+var ErrAccountNotConnected = errors.New("no calendar account connected for this conferencing provider")
+
+// Returned when the custom link isn't a valid url
+var ErrInvalidLink = errors.New("invalid meeting link")
+
+// Returned when the calendar account was connected with read only access, so it has to be reconnected to create meetings
+var ErrInsufficientScope = errors.New("calendar account wasn't granted permission to create meetings")
+
+var providers = map[models.ConferencingProvider]Provider{
+	models.GoogleMeetProvider: &GoogleMeet{},
+	models.TeamsProvider:      &Teams{},
+	models.JitsiProvider:      &Jitsi{},
+	models.CustomLinkProvider: &CustomLink{},
+}
+
+// Returns the provider with the given name, and false if there is no such provider
+func GetProvider(name models.ConferencingProvider) (Provider, bool) {
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+// Returns the key of the user's calendar account of the given type, preferring the account they signed in with.
+// Returns an empty string if the user has no such account
+func getCalendarAccountKey(user *models.User, calendarType models.CalendarType) string {
+	var calendarAccountKey string
+	for accountKey, account := range user.CalendarAccounts {
+		if account.CalendarType == calendarType && account.OAuth2CalendarAuth != nil {
+			if len(calendarAccountKey) == 0 || accountKey == utils.GetPrimaryAccountKey(user) {
+				calendarAccountKey = accountKey
+			}
+		}
+	}
+	return calendarAccountKey
+}
+
+// Returns the refreshed auth of the calendar account that a meeting was created in, or nil if the account was removed
+func getCalendarEventAuth(user *models.User, calendarEvent *models.ConferenceCalendarEvent) *models.OAuth2CalendarAuth {
+	if user == nil || calendarEvent == nil {
+		return nil
+	}
+	account, ok := user.CalendarAccounts[calendarEvent.CalendarAccountKey]
+	if !ok || account.OAuth2CalendarAuth == nil {
+		return nil
+	}
+
+	auth.RefreshUserTokenIfNecessary(user, models.Set[string]{calendarEvent.CalendarAccountKey: {}})
+	return user.CalendarAccounts[calendarEvent.CalendarAccountKey].OAuth2CalendarAuth
+}
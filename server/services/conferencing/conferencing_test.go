@@ -0,0 +1,46 @@
+package conferencing
+
+import (
+	"strings"
+	"testing"
+
+	"schej.it/server/models"
+)
+
+func TestJitsiMeetingLink(t *testing.T) {
+	t.Setenv("JITSI_URL_TEMPLATE", "https://jitsi.example.org/{room}")
+
+	provider, ok := GetProvider(models.JitsiProvider)
+	if !ok {
+		t.Fatal("expected jitsi provider to be registered")
+	}
+
+	meeting, err := provider.CreateMeeting(MeetingOptions{Title: "team sync!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(meeting.Link, "https://jitsi.example.org/TeamSync-") {
+		t.Errorf("expected link to use the url template and title, got %s", meeting.Link)
+	}
+	if meeting.CalendarEvent != nil {
+		t.Errorf("expected jitsi meetings not to create calendar events")
+	}
+
+	other, _ := provider.CreateMeeting(MeetingOptions{Title: "team sync!"})
+	if meeting.Link == other.Link {
+		t.Errorf("expected links for meetings with the same title to be different")
+	}
+}
+
+func TestCustomMeetingLink(t *testing.T) {
+	provider, _ := GetProvider(models.CustomLinkProvider)
+
+	meeting, err := provider.CreateMeeting(MeetingOptions{Link: "https://zoom.us/j/123"})
+	if err != nil || meeting.Link != "https://zoom.us/j/123" {
+		t.Errorf("expected custom link to be saved as is, got %s (%v)", meeting.Link, err)
+	}
+
+	if _, err := provider.CreateMeeting(MeetingOptions{Link: "javascript:alert(1)"}); err != ErrInvalidLink {
+		t.Errorf("expected non http links to be rejected, got %v", err)
+	}
+}
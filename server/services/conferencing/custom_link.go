@@ -0,0 +1,27 @@
+package conferencing
+
+import (
+	"net/url"
+
+	"schej.it/server/models"
+)
+
+// Uses a link the user already has, e.g. a recurring Zoom room
+type CustomLink struct{}
+
+func (provider *CustomLink) CreateMeeting(options MeetingOptions) (Meeting, error) {
+	link, err := url.Parse(options.Link)
+	if err != nil || (link.Scheme != "https" && link.Scheme != "http") || len(link.Host) == 0 {
+		return Meeting{}, ErrInvalidLink
+	}
+
+	return Meeting{Link: link.String()}, nil
+}
+
+func (provider *CustomLink) DeleteMeeting(user *models.User, calendarEvent *models.ConferenceCalendarEvent) error {
+	return nil
+}
+
+func (provider *CustomLink) RequiresTime() bool {
+	return false
+}
@@ -0,0 +1,102 @@
+package conferencing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	googlecalendar "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"schej.it/server/models"
+	"schej.it/server/services/auth"
+	"schej.it/server/services/google_api"
+)
+
+// Creates Google Meet meetings as events in the user's primary Google calendar
+type GoogleMeet struct{}
+
+func (provider *GoogleMeet) CreateMeeting(options MeetingOptions) (Meeting, error) {
+	calendarAccountKey := getCalendarAccountKey(options.User, models.GoogleCalendarType)
+	if len(calendarAccountKey) == 0 {
+		return Meeting{}, ErrAccountNotConnected
+	}
+
+	// The calendar service refreshes its own access token, but refresh here too so the stored token is kept up to date
+	auth.RefreshUserTokenIfNecessary(options.User, models.Set[string]{calendarAccountKey: {}})
+	calendarAuth := options.User.CalendarAccounts[calendarAccountKey].OAuth2CalendarAuth
+	if calendarAuth == nil || calendarAuth.RefreshToken == "" {
+		return Meeting{}, ErrAccountNotConnected
+	}
+
+	calendarService, err := google_api.GetCalendarService(calendarAuth.RefreshToken)
+	if err != nil {
+		return Meeting{}, err
+	}
+
+	calendarEvent := &googlecalendar.Event{
+		Summary: options.Title,
+		Start: &googlecalendar.EventDateTime{
+			DateTime: options.StartTime.Format(time.RFC3339),
+		},
+		End: &googlecalendar.EventDateTime{
+			DateTime: options.EndTime.Format(time.RFC3339),
+		},
+		ConferenceData: &googlecalendar.ConferenceData{
+			CreateRequest: &googlecalendar.CreateConferenceRequest{
+				RequestId: uuid.New().String(),
+				ConferenceSolutionKey: &googlecalendar.ConferenceSolutionKey{
+					Type: "hangoutsMeet",
+				},
+			},
+		},
+	}
+
+	// conferenceDataVersion=1 is needed to get the Meet link back
+	createdEvent, err := calendarService.Events.Insert("primary", calendarEvent).ConferenceDataVersion(1).Do()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden && strings.Contains(apiErr.Header.Get("WWW-Authenticate"), "insufficient_scope") {
+		return Meeting{}, fmt.Errorf("%w: %v", ErrInsufficientScope, err)
+	} else if err != nil {
+		return Meeting{}, err
+	}
+
+	if createdEvent.ConferenceData == nil || createdEvent.HangoutLink == "" {
+		return Meeting{}, fmt.Errorf("google calendar event %s was created without a meet link", createdEvent.Id)
+	}
+
+	return Meeting{
+		Link: createdEvent.HangoutLink,
+		CalendarEvent: &models.ConferenceCalendarEvent{
+			UserId:             options.User.Id,
+			CalendarAccountKey: calendarAccountKey,
+			EventId:            createdEvent.Id,
+		},
+	}, nil
+}
+
+func (provider *GoogleMeet) DeleteMeeting(user *models.User, calendarEvent *models.ConferenceCalendarEvent) error {
+	calendarAuth := getCalendarEventAuth(user, calendarEvent)
+	if calendarAuth == nil || calendarAuth.RefreshToken == "" {
+		return ErrAccountNotConnected
+	}
+
+	calendarService, err := google_api.GetCalendarService(calendarAuth.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	err = calendarService.Events.Delete("primary", calendarEvent.EventId).Do()
+	// The event was already deleted by the user
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone) {
+		return nil
+	}
+	return err
+}
+
+func (provider *GoogleMeet) RequiresTime() bool {
+	return true
+}
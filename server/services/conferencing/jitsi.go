@@ -0,0 +1,59 @@
+package conferencing
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+const defaultJitsiUrlTemplate = "https://meet.jit.si/{room}"
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// Creates Jitsi meeting links, which don't need an account or any api calls.
+// Set JITSI_URL_TEMPLATE to use a self-hosted Jitsi server, e.g. "https://jitsi.example.org/{room}"
+type Jitsi struct{}
+
+func (provider *Jitsi) CreateMeeting(options MeetingOptions) (Meeting, error) {
+	// Jitsi rooms are public to anyone with the link, so add a random suffix to make it hard to guess
+	token, err := utils.GenerateToken(9)
+	if err != nil {
+		return Meeting{}, err
+	}
+	room := fmt.Sprintf("%s-%s", getJitsiRoomPrefix(options.Title), token)
+
+	template := os.Getenv("JITSI_URL_TEMPLATE")
+	if len(template) == 0 {
+		template = defaultJitsiUrlTemplate
+	}
+
+	return Meeting{Link: strings.ReplaceAll(template, "{room}", url.PathEscape(room))}, nil
+}
+
+func (provider *Jitsi) DeleteMeeting(user *models.User, calendarEvent *models.ConferenceCalendarEvent) error {
+	return nil
+}
+
+func (provider *Jitsi) RequiresTime() bool {
+	return false
+}
+
+// Returns a readable room name based on the title of the meeting, e.g. "Team sync!" becomes "TeamSync"
+func getJitsiRoomPrefix(title string) string {
+	var prefix strings.Builder
+	for _, word := range nonAlphanumericRegex.Split(title, -1) {
+		if len(word) > 0 {
+			prefix.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	if prefix.Len() == 0 {
+		return "Schej"
+	}
+	return prefix.String()
+}
@@ -0,0 +1,52 @@
+package conferencing
+
+import (
+	"errors"
+	"fmt"
+
+	"schej.it/server/models"
+	"schej.it/server/services/auth"
+	"schej.it/server/services/microsoftgraph"
+)
+
+// Creates Teams meetings as events in the user's Outlook calendar
+type Teams struct{}
+
+func (provider *Teams) CreateMeeting(options MeetingOptions) (Meeting, error) {
+	calendarAccountKey := getCalendarAccountKey(options.User, models.OutlookCalendarType)
+	if len(calendarAccountKey) == 0 {
+		return Meeting{}, ErrAccountNotConnected
+	}
+
+	auth.RefreshUserTokenIfNecessary(options.User, models.Set[string]{calendarAccountKey: {}})
+	calendarAuth := options.User.CalendarAccounts[calendarAccountKey].OAuth2CalendarAuth
+
+	onlineMeeting, err := microsoftgraph.CreateOnlineMeetingEvent(nil, calendarAuth, options.Title, options.StartTime, options.EndTime)
+	if errors.Is(err, microsoftgraph.ErrInsufficientScope) {
+		return Meeting{}, fmt.Errorf("%w: %v", ErrInsufficientScope, err)
+	} else if err != nil {
+		return Meeting{}, err
+	}
+
+	return Meeting{
+		Link: onlineMeeting.JoinUrl,
+		CalendarEvent: &models.ConferenceCalendarEvent{
+			UserId:             options.User.Id,
+			CalendarAccountKey: calendarAccountKey,
+			EventId:            onlineMeeting.EventId,
+		},
+	}, nil
+}
+
+func (provider *Teams) DeleteMeeting(user *models.User, calendarEvent *models.ConferenceCalendarEvent) error {
+	calendarAuth := getCalendarEventAuth(user, calendarEvent)
+	if calendarAuth == nil {
+		return ErrAccountNotConnected
+	}
+
+	return microsoftgraph.DeleteOnlineMeetingEvent(nil, calendarAuth, calendarEvent.EventId)
+}
+
+func (provider *Teams) RequiresTime() bool {
+	return true
+}
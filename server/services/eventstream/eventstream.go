@@ -0,0 +1,172 @@
+package eventstream
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+type MessageType string
+
+const (
+	ResponseAdded   MessageType = "responseAdded"
+	ResponseUpdated MessageType = "responseUpdated"
+	ResponseDeleted MessageType = "responseDeleted"
+	EventEdited     MessageType = "eventEdited"
+	EventScheduled  MessageType = "eventScheduled"
+
+	EventUnscheduled MessageType = "eventUnscheduled"
+	EventClosed      MessageType = "eventClosed"
+	EventDeleted     MessageType = "eventDeleted"
+	CommentAdded     MessageType = "commentAdded"
+	CommentDeleted   MessageType = "commentDeleted"
+	PollsUpdated     MessageType = "pollsUpdated"
+)
+
+// A notification that something about an event changed. Clients refetch the event or its responses to get the change
+type Message struct {
+	Type MessageType `json:"type"`
+
+	// The key of the response in the responses map, for response messages
+	UserId string `json:"userId,omitempty"`
+}
+
+// Number of messages that can be queued for a subscriber before further messages to it are dropped
+const bufferSize = 16
+
+// How long to wait before reopening the change stream of messages after it fails
+const watchRetryDelay = 5 * time.Second
+
+var (
+	mutex       sync.RWMutex
+	subscribers = make(map[string]map[chan Message]struct{})
+
+	// Whether messages are published through the database so that every server instance receives them
+	shared atomic.Bool
+)
+
+// Starts delivering the messages published by every server instance to the subscribers of this instance, by watching
+// the messages collection with a change stream. If the database doesn't support change streams, only messages
+// published by this instance are delivered. Returns a function that stops watching
+func Init() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := db.WatchEventMessages(ctx, nil)
+	if err != nil {
+		logger.StdErr.Printf("Live updates are only delivered within this server instance: %v", err)
+		return cancel
+	}
+
+	shared.Store(true)
+	go watch(ctx, stream)
+
+	return func() {
+		shared.Store(false)
+		cancel()
+	}
+}
+
+// Delivers the messages from the change stream until the context is cancelled, reopening the stream where it left off if it fails
+func watch(ctx context.Context, stream *mongo.ChangeStream) {
+	for {
+		for stream.Next(ctx) {
+			change := struct {
+				FullDocument models.EventMessage `bson:"fullDocument"`
+			}{}
+			if err := stream.Decode(&change); err != nil {
+				logger.StdErr.Println(err)
+				continue
+			}
+			deliver(change.FullDocument.EventId, Message{Type: MessageType(change.FullDocument.Type), UserId: change.FullDocument.UserId})
+		}
+
+		err := stream.Err()
+		resumeToken := stream.ResumeToken()
+		stream.Close(context.Background())
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.StdErr.Println("Reopening the change stream of live updates after error:", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryDelay):
+			}
+
+			stream, err = db.WatchEventMessages(ctx, resumeToken)
+			if err == nil {
+				break
+			}
+		}
+	}
+}
+
+// Subscribes to live updates to the event with the given id, published by any server instance once Init is called.
+// The returned function must be called to unsubscribe
+func Subscribe(eventId string) (<-chan Message, func()) {
+	messages := make(chan Message, bufferSize)
+
+	mutex.Lock()
+	if subscribers[eventId] == nil {
+		subscribers[eventId] = make(map[chan Message]struct{})
+	}
+	subscribers[eventId][messages] = struct{}{}
+	mutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			delete(subscribers[eventId], messages)
+			if len(subscribers[eventId]) == 0 {
+				delete(subscribers, eventId)
+			}
+		})
+	}
+
+	return messages, unsubscribe
+}
+
+// Sends the message to everyone subscribed to the event with the given id, on every server instance once Init is called.
+// Never blocks on subscribers, and messages to subscribers that aren't keeping up are dropped
+func Publish(eventId string, message Message) {
+	if shared.Load() {
+		err := db.InsertEventMessage(&models.EventMessage{
+			EventId:   eventId,
+			CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+			Type:      string(message.Type),
+			UserId:    message.UserId,
+		})
+		if err == nil {
+			return
+		}
+
+		// At least deliver the message to the subscribers of this instance
+		logger.StdErr.Println(err)
+	}
+
+	deliver(eventId, message)
+}
+
+// Sends the message to the subscribers of this instance to the event with the given id without blocking
+func deliver(eventId string, message Message) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	for messages := range subscribers[eventId] {
+		select {
+		case messages <- message:
+		default:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package eventstream
+
+import (
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	messages, unsubscribe := Subscribe("a")
+	otherMessages, unsubscribeOther := Subscribe("b")
+	defer unsubscribeOther()
+
+	Publish("a", Message{Type: ResponseAdded, UserId: "guest"})
+	select {
+	case message := <-messages:
+		if message.Type != ResponseAdded || message.UserId != "guest" {
+			t.Errorf("expected the published message, got %+v", message)
+		}
+	default:
+		t.Fatal("expected a message to be published")
+	}
+	select {
+	case message := <-otherMessages:
+		t.Errorf("expected no message for another event, got %+v", message)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if n := len(subscribers["a"]); n != 0 {
+		t.Errorf("expected no subscribers after unsubscribing, got %d", n)
+	}
+	Publish("a", Message{Type: EventEdited})
+	if len(messages) != 0 {
+		t.Error("expected no messages after unsubscribing")
+	}
+}
+
+func TestPublishDoesNotBlock(t *testing.T) {
+	messages, unsubscribe := Subscribe("c")
+	defer unsubscribe()
+
+	for i := 0; i < 2*bufferSize; i++ {
+		Publish("c", Message{Type: ResponseUpdated})
+	}
+	if len(messages) != bufferSize {
+		t.Errorf("expected %d queued messages, got %d", bufferSize, len(messages))
+	}
+}
@@ -12,6 +12,7 @@ import (
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2beta3"
 	"cloud.google.com/go/cloudtasks/apiv2beta3/cloudtaskspb"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"google.golang.org/api/option"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"schej.it/server/logger"
@@ -22,6 +23,11 @@ import (
 var TasksClient *cloudtasks.Client
 
 func InitTasks() func() {
+	// Without the secret the /tasks routes reject every task, so events would never close
+	if len(os.Getenv("TASKS_SECRET")) == 0 {
+		logger.StdErr.Panicln("TASKS_SECRET is not set")
+	}
+
 	ctx := context.Background()
 
 	var err error
@@ -126,6 +132,38 @@ func CreateEmailTask(email string, ownerName string, eventName string, eventId s
 	return taskIds
 }
 
+// Schedules a task that calls the /tasks/close-event route to close the event with the given id at the given time
+func CreateCloseEventTask(eventId string, closesAt primitive.DateTime) {
+	body, err := json.Marshal(bson.M{
+		"eventId":  eventId,
+		"closesAt": closesAt,
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	_, err = TasksClient.CreateTask(context.Background(), &cloudtaskspb.CreateTaskRequest{
+		Parent: "projects/schej-it/locations/us-central1/queues/CloseEvent",
+		Task: &cloudtaskspb.Task{
+			ScheduleTime: timestamppb.New(closesAt.Time()),
+			PayloadType: &cloudtaskspb.Task_HttpRequest{
+				HttpRequest: &cloudtaskspb.HttpRequest{
+					Url:        fmt.Sprintf("%s/api/tasks/close-event", utils.GetBaseUrl()),
+					HttpMethod: cloudtaskspb.HttpMethod_POST,
+					Headers: map[string]string{
+						"Content-Type":   "application/json",
+						"X-Tasks-Secret": os.Getenv("TASKS_SECRET"),
+					},
+					Body: body,
+				},
+			},
+		},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
 func DeleteEmailTask(taskId string) {
 	err := TasksClient.DeleteTask(context.Background(), &cloudtaskspb.DeleteTaskRequest{
 		Name: taskId,
@@ -0,0 +1,203 @@
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+const ProductId = "-//schej.it//schej.it//EN"
+
+// iTIP methods, as defined in RFC 5546
+type Method string
+
+const (
+	PUBLISH Method = "PUBLISH"
+	REQUEST Method = "REQUEST"
+	CANCEL  Method = "CANCEL"
+)
+
+// Someone who is either organizing or attending an event
+type Participant struct {
+	Name  string
+	Email string
+}
+
+type ScheduledEventOptions struct {
+	Method    Method
+	Organizer *Participant
+	Attendees []Participant
+}
+
+// Returns a new calendar with the required properties set
+func NewCalendar(method Method) *ical.Calendar {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropProductID, ProductId)
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropCalendarScale, "GREGORIAN")
+	if len(method) > 0 {
+		cal.Props.SetText(ical.PropMethod, string(method))
+	}
+	return cal
+}
+
+// Returns the VEVENT for the scheduled time of the given event, or nil if the event hasn't been scheduled
+func NewScheduledEvent(event *models.Event, options ScheduledEventOptions) *ical.Event {
+	if event.ScheduledEvent == nil {
+		return nil
+	}
+
+	vevent := ical.NewEvent()
+	vevent.Props.SetText(ical.PropUID, GetUID(event))
+	vevent.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	vevent.Props.SetDateTime(ical.PropDateTimeStart, event.ScheduledEvent.StartDate.Time().UTC())
+	vevent.Props.SetDateTime(ical.PropDateTimeEnd, event.ScheduledEvent.EndDate.Time().UTC())
+	vevent.Props.SetText(ical.PropSummary, event.Name)
+
+	// Calendar clients only apply updates with a higher sequence number
+	setRawProp(vevent.Props, ical.PropSequence, strconv.Itoa(event.ScheduleSequence))
+
+	if description := utils.Coalesce(event.Description); len(description) > 0 {
+		vevent.Props.SetText(ical.PropDescription, description)
+	}
+	if meetLink := utils.Coalesce(event.MeetLink); len(meetLink) > 0 {
+		vevent.Props.SetText(ical.PropLocation, meetLink)
+	}
+	if eventUrl, err := url.Parse(GetEventUrl(event)); err == nil {
+		vevent.Props.SetURI(ical.PropURL, eventUrl)
+	}
+
+	if options.Method == CANCEL {
+		vevent.SetStatus(ical.EventCancelled)
+	} else {
+		vevent.SetStatus(ical.EventConfirmed)
+	}
+
+	if options.Organizer != nil {
+		vevent.Props.Set(newParticipantProp(ical.PropOrganizer, *options.Organizer))
+	}
+	for _, attendee := range options.Attendees {
+		prop := newParticipantProp(ical.PropAttendee, attendee)
+		prop.Params.Set(ical.ParamRole, "REQ-PARTICIPANT")
+		if options.Method == REQUEST {
+			prop.Params.Set(ical.ParamParticipationStatus, "NEEDS-ACTION")
+			prop.Params.Set(ical.ParamRSVP, "TRUE")
+		}
+		vevent.Props.Add(prop)
+	}
+
+	return vevent
+}
+
+// Returns a new calendar meant to be subscribed to, which clients refresh every hour
+func NewFeedCalendar(name string) *ical.Calendar {
+	cal := NewCalendar(PUBLISH)
+	cal.Props.SetText(ical.PropName, name)
+	setRawProp(cal.Props, "X-WR-CALNAME", name)
+	refreshInterval := ical.NewProp(ical.PropRefreshInterval)
+	refreshInterval.Params.Set(ical.ParamValue, string(ical.ValueDuration))
+	refreshInterval.Value = "PT1H"
+	cal.Props.Set(refreshInterval)
+	setRawProp(cal.Props, "X-PUBLISHED-TTL", "PT1H")
+	return cal
+}
+
+// Returns all day placeholder VEVENTs for each of the dates of an event that hasn't been scheduled yet
+func NewPendingPollEvents(event *models.Event) []*ical.Event {
+	vevents := make([]*ical.Event, 0)
+	if event.ScheduledEvent != nil || event.Type != models.SPECIFIC_DATES {
+		return vevents
+	}
+
+	// Dates are stored as the start time on each day in the event's time zone, so the UTC date can be the day before or after
+	loc := utils.GetLocation(event.TimeZone)
+	eventUrl, _ := url.Parse(GetEventUrl(event))
+	for _, date := range event.Dates {
+		day := utils.GetLocalDay(date.Time(), loc)
+
+		vevent := ical.NewEvent()
+		vevent.Props.SetText(ical.PropUID, fmt.Sprintf("%s-%s@schej.it", event.Id.Hex(), day.Format("20060102")))
+		vevent.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+		vevent.Props.SetDate(ical.PropDateTimeStart, day)
+		vevent.Props.SetDate(ical.PropDateTimeEnd, day.AddDate(0, 0, 1))
+		vevent.Props.SetText(ical.PropSummary, fmt.Sprintf("Poll: %s", event.Name))
+		vevent.Props.SetText(ical.PropTransparency, "TRANSPARENT")
+		vevent.SetStatus(ical.EventTentative)
+		if eventUrl != nil {
+			vevent.Props.SetURI(ical.PropURL, eventUrl)
+		}
+
+		vevents = append(vevents, vevent)
+	}
+
+	return vevents
+}
+
+// Returns the encoded VCALENDAR containing the scheduled time of the given event
+func GetScheduledEventIcs(event *models.Event, options ScheduledEventOptions) ([]byte, error) {
+	vevent := NewScheduledEvent(event, options)
+	if vevent == nil {
+		return nil, fmt.Errorf("event %s has not been scheduled", event.GetId())
+	}
+
+	cal := NewCalendar(options.Method)
+	cal.Children = append(cal.Children, vevent.Component)
+
+	return Encode(cal)
+}
+
+func Encode(cal *ical.Calendar) ([]byte, error) {
+	// A calendar must contain at least one component to be valid, so add an empty UTC timezone if there are none
+	if len(cal.Children) == 0 {
+		standard := ical.NewComponent(ical.CompTimezoneStandard)
+		setRawProp(standard.Props, ical.PropDateTimeStart, "19700101T000000")
+		setRawProp(standard.Props, ical.PropTimezoneOffsetFrom, "+0000")
+		setRawProp(standard.Props, ical.PropTimezoneOffsetTo, "+0000")
+
+		timezone := ical.NewComponent(ical.CompTimezone)
+		timezone.Props.SetText(ical.PropTimezoneID, "UTC")
+		timezone.Children = append(timezone.Children, standard)
+		cal.Children = append(cal.Children, timezone)
+	}
+
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Returns a UID that stays the same across reschedules so calendar clients update the existing event
+func GetUID(event *models.Event) string {
+	return fmt.Sprintf("%s@schej.it", event.Id.Hex())
+}
+
+func GetEventUrl(event *models.Event) string {
+	if event.Type == models.GROUP {
+		return fmt.Sprintf("%s/g/%s", utils.GetBaseUrl(), event.GetId())
+	}
+	return fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+}
+
+func newParticipantProp(name string, participant Participant) *ical.Prop {
+	prop := ical.NewProp(name)
+	prop.SetValueType(ical.ValueCalendarAddress)
+	prop.Value = fmt.Sprintf("mailto:%s", participant.Email)
+	if len(participant.Name) > 0 {
+		prop.Params.Set(ical.ParamCommonName, participant.Name)
+	}
+	return prop
+}
+
+// Sets the property to the given value as is, without setting a VALUE parameter
+func setRawProp(props ical.Props, name string, value string) {
+	prop := ical.NewProp(name)
+	prop.Value = value
+	props.Set(prop)
+}
@@ -0,0 +1,64 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetScheduledEventIcs(t *testing.T) {
+	start := time.Date(2024, 5, 6, 16, 0, 0, 0, time.UTC)
+	event := &models.Event{
+		Id:   primitive.NewObjectID(),
+		Name: "Team sync",
+		ScheduledEvent: &models.CalendarEvent{
+			StartDate: primitive.NewDateTimeFromTime(start),
+			EndDate:   primitive.NewDateTimeFromTime(start.Add(time.Hour)),
+		},
+		ScheduleSequence: 2,
+	}
+
+	data, err := GetScheduledEventIcs(event, ScheduledEventOptions{
+		Method:    REQUEST,
+		Organizer: &Participant{Name: "Jonathan", Email: "owner@schej.it"},
+		Attendees: []Participant{{Email: "attendee@schej.it"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ics := string(data)
+	for _, expected := range []string{
+		"METHOD:REQUEST",
+		"UID:" + GetUID(event),
+		"DTSTART:20240506T160000Z",
+		"DTEND:20240506T170000Z",
+		"SEQUENCE:2",
+		"ORGANIZER;CN=Jonathan:mailto:owner@schej.it",
+		"mailto:attendee@schej.it",
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("expected ics to contain %q, got:\n%s", expected, ics)
+		}
+	}
+}
+
+func TestGetScheduledEventIcsNotScheduled(t *testing.T) {
+	_, err := GetScheduledEventIcs(&models.Event{Id: primitive.NewObjectID()}, ScheduledEventOptions{Method: PUBLISH})
+	if err == nil {
+		t.Error("expected an error for an event that hasn't been scheduled")
+	}
+}
+
+func TestEncodeEmptyFeed(t *testing.T) {
+	data, err := Encode(NewFeedCalendar("schej.it"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "X-WR-CALNAME:schej.it") {
+		t.Errorf("expected feed to contain its name, got:\n%s", data)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
 	"strconv"
 
@@ -106,8 +108,15 @@ func DoesUserExist(email string) (bool, *int) {
 	}
 }
 
-// Send a transactional email using the specified template and data
-func SendEmail(email string, templateId int, data bson.M) {
+// A file to attach to a transactional email
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// Send a transactional email using the specified template and data, with optional attachments
+func SendEmail(email string, templateId int, data bson.M, attachments ...Attachment) {
 	if os.Getenv("LISTMONK_ENABLED") == "false" {
 		return
 	}
@@ -129,21 +138,62 @@ func SendEmail(email string, templateId int, data bson.M) {
 		return
 	}
 
+	// Attachments must be sent as a multipart form, with the JSON body in the "data" field
+	contentType := "application/json"
+	if len(attachments) > 0 {
+		body, contentType, err = getMultipartBody(body, attachments)
+		if err != nil {
+			logger.StdErr.Println(err)
+			return
+		}
+	}
+
 	// Construct request
 	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/tx", listmonkUrl), bytes.NewBuffer(body))
 	req.SetBasicAuth(listmonkUsername, listmonkPassword)
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 
 	// Execute request
 	response, err := http.DefaultClient.Do(req)
 	if err != nil {
 		logger.StdErr.Println(err)
+		return
 	}
 	defer response.Body.Close()
 }
 
+// Returns the multipart form body and its content type
+func getMultipartBody(data []byte, attachments []Attachment) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	if err := writer.WriteField("data", string(data)); err != nil {
+		return nil, "", err
+	}
+
+	for _, attachment := range attachments {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, attachment.FileName))
+		header.Set("Content-Type", attachment.ContentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(attachment.Data); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
 // Send a transactional email using the specified template and data. Adds subscriber if they don't exist
-func SendEmailAddSubscriberIfNotExist(email string, templateId int, data bson.M) {
+func SendEmailAddSubscriberIfNotExist(email string, templateId int, data bson.M, attachments ...Attachment) {
 	if os.Getenv("LISTMONK_ENABLED") == "false" {
 		return
 	}
@@ -152,5 +202,5 @@ func SendEmailAddSubscriberIfNotExist(email string, templateId int, data bson.M)
 		AddUserToListmonk(email, "", "", "", nil)
 	}
 
-	SendEmail(email, templateId, data)
+	SendEmail(email, templateId, data, attachments...)
 }
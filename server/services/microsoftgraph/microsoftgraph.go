@@ -2,6 +2,13 @@ package microsoftgraph
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
 
 	"schej.it/server/logger"
 	"schej.it/server/models"
@@ -40,3 +47,127 @@ func GetUserInfo(user *models.User, calendarAuth *models.OAuth2CalendarAuth) Use
 		Email:     userResponse.Mail,
 	}
 }
+
+type OnlineMeeting struct {
+	// Id of the calendar event the meeting was created for
+	EventId string
+	JoinUrl string
+}
+
+// Creates an event with a Teams meeting in the user's default calendar and returns the meeting's join url
+func CreateOnlineMeetingEvent(user *models.User, calendarAuth *models.OAuth2CalendarAuth, title string, startTime time.Time, endTime time.Time) (OnlineMeeting, error) {
+	// Work/school accounts and personal accounts support different kinds of Teams meetings
+	onlineMeetingProvider, err := getDefaultOnlineMeetingProvider(user, calendarAuth)
+	if err != nil {
+		return OnlineMeeting{}, err
+	}
+
+	// Custom time format for Outlook date-time strings
+	const outlookTimeFormat = "2006-01-02T15:04:05.0000000"
+
+	response := services.CallApi(
+		user,
+		calendarAuth,
+		"POST",
+		"https://graph.microsoft.com/v1.0/me/events",
+		&bson.M{
+			"subject":               title,
+			"start":                 bson.M{"dateTime": startTime.UTC().Format(outlookTimeFormat), "timeZone": "UTC"},
+			"end":                   bson.M{"dateTime": endTime.UTC().Format(outlookTimeFormat), "timeZone": "UTC"},
+			"isOnlineMeeting":       true,
+			"onlineMeetingProvider": onlineMeetingProvider,
+		},
+	)
+	defer response.Body.Close()
+
+	eventResponse := struct {
+		Id            string `json:"id"`
+		OnlineMeeting *struct {
+			JoinUrl string `json:"joinUrl"`
+		} `json:"onlineMeeting"`
+		Error bson.M `json:"error"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&eventResponse); err != nil {
+		return OnlineMeeting{}, err
+	}
+
+	if eventResponse.Error != nil {
+		if response.StatusCode == http.StatusForbidden {
+			return OnlineMeeting{}, fmt.Errorf("%w: %v", ErrInsufficientScope, eventResponse.Error)
+		}
+		return OnlineMeeting{}, fmt.Errorf("error creating Outlook online meeting: %v", eventResponse.Error)
+	}
+	if eventResponse.OnlineMeeting == nil || len(eventResponse.OnlineMeeting.JoinUrl) == 0 {
+		return OnlineMeeting{}, fmt.Errorf("outlook event %s was created without an online meeting", eventResponse.Id)
+	}
+
+	return OnlineMeeting{
+		EventId: eventResponse.Id,
+		JoinUrl: eventResponse.OnlineMeeting.JoinUrl,
+	}, nil
+}
+
+// Deletes an event created by CreateOnlineMeetingEvent, which also cancels its online meeting
+func DeleteOnlineMeetingEvent(user *models.User, calendarAuth *models.OAuth2CalendarAuth, eventId string) error {
+	response := services.CallApi(
+		user,
+		calendarAuth,
+		"DELETE",
+		fmt.Sprintf("https://graph.microsoft.com/v1.0/me/events/%s", url.PathEscape(eventId)),
+		nil,
+	)
+	defer response.Body.Close()
+
+	// The event was already deleted by the user
+	if response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("error deleting Outlook online meeting: %s", response.Status)
+	}
+
+	return nil
+}
+
+// Returns the online meeting provider that the user's default calendar uses, e.g. "teamsForBusiness" or "teamsForPersonal"
+func getDefaultOnlineMeetingProvider(user *models.User, calendarAuth *models.OAuth2CalendarAuth) (string, error) {
+	response := services.CallApi(
+		user,
+		calendarAuth,
+		"GET",
+		"https://graph.microsoft.com/v1.0/me/calendar?$select=defaultOnlineMeetingProvider,allowedOnlineMeetingProviders",
+		nil,
+	)
+	defer response.Body.Close()
+
+	calendarResponse := struct {
+		DefaultOnlineMeetingProvider  string   `json:"defaultOnlineMeetingProvider"`
+		AllowedOnlineMeetingProviders []string `json:"allowedOnlineMeetingProviders"`
+		Error                         bson.M   `json:"error"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&calendarResponse); err != nil {
+		return "", err
+	}
+
+	if calendarResponse.Error != nil {
+		return "", fmt.Errorf("error fetching Outlook calendar: %v", calendarResponse.Error)
+	}
+
+	if calendarResponse.DefaultOnlineMeetingProvider != "" && calendarResponse.DefaultOnlineMeetingProvider != "unknown" {
+		return calendarResponse.DefaultOnlineMeetingProvider, nil
+	}
+	for _, provider := range calendarResponse.AllowedOnlineMeetingProviders {
+		if provider != "unknown" {
+			return provider, nil
+		}
+	}
+
+	return "", ErrOnlineMeetingsNotSupported
+}
+
+// Returned when the user's account isn't able to create online meetings
+var ErrOnlineMeetingsNotSupported = errors.New("online meetings are not supported for this account")
+
+// Returned when the account was connected with Calendars.Read only, so it has to be reconnected to create events
+var ErrInsufficientScope = errors.New("account wasn't granted permission to create events")
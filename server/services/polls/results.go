@@ -0,0 +1,65 @@
+package polls
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+// The aggregated votes on a poll
+type Results struct {
+	// Number of respondents who voted
+	TotalVotes int `json:"totalVotes"`
+
+	// The results of each option, in the order the options were defined
+	Options []OptionResult `json:"options"`
+}
+
+type OptionResult struct {
+	OptionId primitive.ObjectID `json:"optionId"`
+
+	// Number of respondents who chose or ranked the option
+	Votes int `json:"votes"`
+
+	// What the options are ranked by. The number of votes for choice polls, and the Borda count for ranked polls
+	Score int `json:"score"`
+
+	// Names of the respondents who chose or ranked the option
+	Voters []string `json:"voters"`
+}
+
+// Aggregates the votes on the poll. For ranked polls, an option ranked first out of n options scores n points,
+// second scores n-1 points, and so on, while options a respondent didn't rank score nothing
+func GetResults(poll *models.Poll, votes []models.PollVote) Results {
+	results := Results{Options: make([]OptionResult, len(poll.Options))}
+	indexes := make(map[primitive.ObjectID]int)
+	for i, option := range poll.Options {
+		results.Options[i] = OptionResult{OptionId: option.Id, Voters: make([]string, 0)}
+		indexes[option.Id] = i
+	}
+
+	for _, vote := range votes {
+		if vote.PollId != poll.Id {
+			continue
+		}
+		results.TotalVotes++
+
+		for rank, optionId := range vote.OptionIds {
+			i, ok := indexes[optionId]
+			if !ok {
+				// The option was removed after the vote
+				continue
+			}
+
+			result := &results.Options[i]
+			result.Votes++
+			result.Voters = append(result.Voters, vote.Name)
+			if poll.Type == models.RankedPoll {
+				result.Score += len(poll.Options) - rank
+			} else {
+				result.Score++
+			}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,47 @@
+package polls
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetResults(t *testing.T) {
+	park, cafe, office := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	poll := &models.Poll{
+		Id:      primitive.NewObjectID(),
+		Options: []models.PollOption{{Id: park, Text: "Park"}, {Id: cafe, Text: "Cafe"}, {Id: office, Text: "Office"}},
+	}
+	votes := []models.PollVote{
+		{PollId: poll.Id, Name: "a", OptionIds: []primitive.ObjectID{cafe, park}},
+		{PollId: poll.Id, Name: "b", OptionIds: []primitive.ObjectID{park, office, cafe}},
+		{PollId: poll.Id, Name: "c", OptionIds: []primitive.ObjectID{cafe}},
+		{PollId: primitive.NewObjectID(), Name: "other poll", OptionIds: []primitive.ObjectID{office}},
+	}
+
+	tests := []struct {
+		pollType models.PollType
+		scores   []int
+	}{
+		{models.MultipleChoicePoll, []int{2, 3, 1}},
+		{models.RankedPoll, []int{2 + 3, 3 + 1 + 3, 2}},
+	}
+	for _, test := range tests {
+		poll.Type = test.pollType
+		results := GetResults(poll, votes)
+		if results.TotalVotes != 3 {
+			t.Errorf("%s: expected 3 votes, got %d", test.pollType, results.TotalVotes)
+		}
+		for i, expected := range test.scores {
+			if results.Options[i].Score != expected {
+				t.Errorf("%s: expected option %d to score %d, got %d", test.pollType, i, expected, results.Options[i].Score)
+			}
+		}
+	}
+
+	results := GetResults(poll, votes)
+	if voters := results.Options[1].Voters; len(voters) != 3 || voters[0] != "a" || voters[2] != "c" {
+		t.Errorf("expected everyone who ranked the cafe to be listed, got %v", voters)
+	}
+}
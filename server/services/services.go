@@ -36,6 +36,9 @@ func CallApi(user *models.User, calendarAuth *models.OAuth2CalendarAuth, method
 		req, _ = http.NewRequest(method, url, nil)
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", calendarAuth.AccessToken))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	// Execute request
 	response, err := http.DefaultClient.Do(req)
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/models"
+)
+
+func TestCallApi(t *testing.T) {
+	type request struct {
+		method        string
+		path          string
+		authorization string
+		contentType   string
+		body          bson.M
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{
+			method:        r.Method,
+			path:          r.URL.EscapedPath(),
+			authorization: r.Header.Get("Authorization"),
+			contentType:   r.Header.Get("Content-Type"),
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+				t.Error(err)
+			}
+		}
+		requests = append(requests, req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	calendarAuth := &models.OAuth2CalendarAuth{AccessToken: "token"}
+
+	response := CallApi(nil, calendarAuth, "POST", server.URL+"/calendars/a%2Fb/events", &bson.M{"subject": "Meeting"})
+	response.Body.Close()
+	response = CallApi(nil, calendarAuth, "DELETE", server.URL+"/events/1", nil)
+	response.Body.Close()
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	post := requests[0]
+	if post.method != "POST" || post.path != "/calendars/a%2Fb/events" {
+		t.Errorf("expected POST to /calendars/a%%2Fb/events, got %s to %s", post.method, post.path)
+	}
+	if post.authorization != "Bearer token" {
+		t.Errorf("expected bearer authorization, got %q", post.authorization)
+	}
+	if post.contentType != "application/json" {
+		t.Errorf("expected json content type for a request with a body, got %q", post.contentType)
+	}
+	if post.body["subject"] != "Meeting" {
+		t.Errorf("expected the body to be sent, got %v", post.body)
+	}
+
+	if del := requests[1]; del.contentType != "" {
+		t.Errorf("expected no content type for a request without a body, got %q", del.contentType)
+	}
+}
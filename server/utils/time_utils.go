@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"time"
+)
+
+// Returns whether the given string is a valid IANA time zone name (e.g. "America/Los_Angeles").
+// "Local" is rejected since it is the time zone of the server rather than of the user
+func IsValidTimeZone(timeZone string) bool {
+	if len(timeZone) == 0 || timeZone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(timeZone)
+	return err == nil
+}
+
+// Returns the location of the given IANA time zone, falling back to UTC if it is unset or invalid
+func GetLocation(timeZone *string) *time.Location {
+	if timeZone == nil || len(*timeZone) == 0 {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Returns the given time moved by the given number of weeks, keeping the same wall clock time in loc.
+// This means the returned time is an hour off from adding a fixed duration if a DST transition happens in between
+func AddWeeks(t time.Time, weeks int, loc *time.Location) time.Time {
+	return AddDays(t, 7*weeks, loc)
+}
+
+// Returns the given time moved by the given number of days, keeping the same wall clock time in loc
+func AddDays(t time.Time, days int, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+days, local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), loc)
+}
+
+// Returns the wall clock time in loc that is the given duration after t, e.g. 8 hours after 9am is 5pm even if the
+// clocks change in between. Adding the duration directly would be an hour off on days with a DST transition
+func AddWallClock(t time.Time, d time.Duration, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond()+int(d), loc)
+}
+
+// Returns the calendar day that the given time falls on in loc, as midnight UTC of that day
+func GetLocalDay(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -209,6 +209,15 @@ func Decrypt(text string) (string, error) {
 	return string(plainText), nil
 }
 
+// Returns a random url safe token generated from the given number of random bytes
+func GenerateToken(numBytes int) (string, error) {
+	b := make([]byte, numBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // ConvertEventToOldFormat converts an event's responses from ResponsesList to ResponsesMap format
 // for backward compatibility with older code
 func ConvertEventToOldFormat(event *models.Event) {